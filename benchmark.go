@@ -0,0 +1,263 @@
+package fstesting
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"path"
+	"sort"
+	"sync"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/absfs/absfs"
+)
+
+// Benchmark is a standard set of benchmarks for absfs.FileSystem
+// implementations, so that different filesystems can be compared with
+// `go test -bench`.
+//
+//	func BenchmarkMyFS(b *testing.B) {
+//		bm := &fstesting.Benchmark{FS: myfs.New(), Latency: true}
+//		b.Run("Create", bm.BenchmarkCreate)
+//		b.Run("SequentialWrite", bm.BenchmarkSequentialWrite)
+//		b.Run("SmallWrites", bm.BenchmarkSmallWrites)
+//		b.Run("RandomRead", bm.BenchmarkRandomRead)
+//		b.Run("Stat", bm.BenchmarkStat)
+//		bm.WriteLatencyTable(os.Stdout)
+//	}
+type Benchmark struct {
+	// FS is the FileSystem being benchmarked.
+	FS absfs.FileSystem
+
+	// TestDir is the directory in FS where benchmark directories are
+	// created. If empty FS.TempDir() is used.
+	TestDir string
+
+	// Latency, if set, times every operation individually and reports the
+	// 50th, 95th and 99th percentile latencies of each benchmark, which
+	// can reveal slow outliers that the mean hides. Timing each operation
+	// adds overhead, so it is off by default.
+	Latency bool
+
+	mu        sync.Mutex
+	latencies map[string]*histogram
+}
+
+// benchmarkFileSize is the size of the file used by the read and write
+// benchmarks.
+const benchmarkFileSize = 1 << 20
+
+// BenchmarkCreate measures creating and closing a new empty file.
+func (bm *Benchmark) BenchmarkCreate(b *testing.B) {
+	testDir := makeTestDir(b, bm.FS, bm.TestDir)
+	b.ResetTimer()
+
+	lat := bm.latency(b, "Create")
+	for i := 0; i < b.N; i++ {
+		name := path.Join(testDir, fmt.Sprintf("create%08d", i))
+		start := lat.start()
+		f, err := bm.FS.Create(name)
+		if err != nil {
+			b.Fatalf("Create(%q): %s", name, err)
+		}
+		f.Close()
+		lat.record(start)
+	}
+}
+
+// BenchmarkSequentialWrite measures writing a file in 4KB chunks.
+func (bm *Benchmark) BenchmarkSequentialWrite(b *testing.B) {
+	testDir := makeTestDir(b, bm.FS, bm.TestDir)
+	name := path.Join(testDir, "sequential")
+	chunk := make([]byte, 4096)
+	f, err := bm.FS.Create(name)
+	if err != nil {
+		b.Fatalf("Create(%q): %s", name, err)
+	}
+	b.Cleanup(func() { f.Close() })
+	b.SetBytes(int64(len(chunk)))
+	b.ResetTimer()
+
+	lat := bm.latency(b, "SequentialWrite")
+	for i := 0; i < b.N; i++ {
+		if i%(benchmarkFileSize/len(chunk)) == 0 {
+			_, err = f.Seek(0, io.SeekStart)
+			if err != nil {
+				b.Fatalf("Seek: %s", err)
+			}
+		}
+		start := lat.start()
+		_, err = f.Write(chunk)
+		if err != nil {
+			b.Fatalf("Write: %s", err)
+		}
+		lat.record(start)
+	}
+}
+
+// BenchmarkSmallWrites measures streaming a file in 64 byte writes and
+// reports allocations, which should be amortized to well under one per
+// write.
+func (bm *Benchmark) BenchmarkSmallWrites(b *testing.B) {
+	testDir := makeTestDir(b, bm.FS, bm.TestDir)
+	name := path.Join(testDir, "small")
+	chunk := make([]byte, 64)
+	f, err := bm.FS.Create(name)
+	if err != nil {
+		b.Fatalf("Create(%q): %s", name, err)
+	}
+	b.Cleanup(func() { f.Close() })
+	b.SetBytes(int64(len(chunk)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	lat := bm.latency(b, "SmallWrites")
+	for i := 0; i < b.N; i++ {
+		if i%(benchmarkFileSize/len(chunk)) == 0 {
+			_, err = f.Seek(0, io.SeekStart)
+			if err != nil {
+				b.Fatalf("Seek: %s", err)
+			}
+		}
+		start := lat.start()
+		_, err = f.Write(chunk)
+		if err != nil {
+			b.Fatalf("Write: %s", err)
+		}
+		lat.record(start)
+	}
+}
+
+// BenchmarkRandomRead measures 4KB reads at random offsets in a 1MB file.
+func (bm *Benchmark) BenchmarkRandomRead(b *testing.B) {
+	testDir := makeTestDir(b, bm.FS, bm.TestDir)
+	name := path.Join(testDir, "random")
+	err := writeAll(bm.FS, name, make([]byte, benchmarkFileSize))
+	if err != nil {
+		b.Fatalf("writing %q: %s", name, err)
+	}
+	f, err := bm.FS.Open(name)
+	if err != nil {
+		b.Fatalf("Open(%q): %s", name, err)
+	}
+	b.Cleanup(func() { f.Close() })
+
+	buf := make([]byte, 4096)
+	rng := rand.New(rand.NewSource(1))
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+
+	lat := bm.latency(b, "RandomRead")
+	for i := 0; i < b.N; i++ {
+		off := rng.Int63n(benchmarkFileSize - int64(len(buf)))
+		start := lat.start()
+		_, err = f.ReadAt(buf, off)
+		if err != nil {
+			b.Fatalf("ReadAt(%d): %s", off, err)
+		}
+		lat.record(start)
+	}
+}
+
+// BenchmarkStat measures Stat of an existing file.
+func (bm *Benchmark) BenchmarkStat(b *testing.B) {
+	testDir := makeTestDir(b, bm.FS, bm.TestDir)
+	name := path.Join(testDir, "stat")
+	err := writeAll(bm.FS, name, []byte("stat"))
+	if err != nil {
+		b.Fatalf("writing %q: %s", name, err)
+	}
+	b.ResetTimer()
+
+	lat := bm.latency(b, "Stat")
+	for i := 0; i < b.N; i++ {
+		start := lat.start()
+		_, err := bm.FS.Stat(name)
+		if err != nil {
+			b.Fatalf("Stat(%q): %s", name, err)
+		}
+		lat.record(start)
+	}
+}
+
+// latency returns the histogram recording the latencies of the operation op
+// of b, or nil if bm.Latency is not set. The percentiles are reported as
+// metrics of b when it finishes, and are kept for WriteLatencyTable.
+func (bm *Benchmark) latency(b *testing.B, op string) *histogram {
+	if !bm.Latency {
+		return nil
+	}
+	h := new(histogram)
+	b.Cleanup(func() {
+		if h.count == 0 {
+			return
+		}
+		b.ReportMetric(float64(h.quantile(0.50)), "p50-ns")
+		b.ReportMetric(float64(h.quantile(0.95)), "p95-ns")
+		b.ReportMetric(float64(h.quantile(0.99)), "p99-ns")
+
+		// The benchmark function runs with increasing b.N, keep the
+		// histogram of the longest run.
+		bm.mu.Lock()
+		defer bm.mu.Unlock()
+		if bm.latencies == nil {
+			bm.latencies = make(map[string]*histogram)
+		}
+		if old := bm.latencies[op]; old == nil || old.count <= h.count {
+			bm.latencies[op] = h
+		}
+	})
+	return h
+}
+
+// WriteLatencyTable writes a table of the latency percentiles of every
+// benchmark run so far with Latency set to w.
+func (bm *Benchmark) WriteLatencyTable(w io.Writer) error {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	ops := make([]string, 0, len(bm.latencies))
+	for op := range bm.latencies {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\tcount\tp50\tp95\tp99\tmax\t")
+	for _, op := range ops {
+		h := bm.latencies[op]
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t\n", op, h.count,
+			h.quantile(0.50), h.quantile(0.95), h.quantile(0.99), h.max)
+	}
+	return tw.Flush()
+}
+
+// AssertLowAllocWrites streams count writes of chunkSize bytes to a new file
+// in fs and fails the test if they average more than maxAllocs allocations
+// each. A FileSystem that reallocates its buffer on every write, rather than
+// growing it geometrically, fails with any maxAllocs below 1.
+func AssertLowAllocWrites(tb testing.TB, fs absfs.FileSystem, chunkSize, count int, maxAllocs float64) {
+	tb.Helper()
+	testDir := makeTestDir(tb, fs, "")
+	name := path.Join(testDir, "allocs")
+	chunk := make([]byte, chunkSize)
+	f, err := fs.Create(name)
+	if err != nil {
+		tb.Fatalf("Create(%q): %s", name, err)
+	}
+	defer f.Close()
+
+	var werr error
+	allocs := testing.AllocsPerRun(count, func() {
+		_, err := f.Write(chunk)
+		if err != nil && werr == nil {
+			werr = err
+		}
+	})
+	if werr != nil {
+		tb.Fatalf("Write: %s", werr)
+	}
+	if allocs > maxAllocs {
+		tb.Errorf("%d byte writes average %.2f allocations, want at most %.2f", chunkSize, allocs, maxAllocs)
+	}
+}
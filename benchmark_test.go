@@ -0,0 +1,55 @@
+package fstesting
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/absfs/absfs"
+)
+
+func TestAssertLowAllocWrites(t *testing.T) {
+	AssertLowAllocWrites(t, osFS{}, 4096, 100, 1)
+}
+
+// allocFS copies every write into a new buffer, as a FileSystem that
+// reallocates its storage on each write would.
+type allocFS struct {
+	osFS
+}
+
+func (allocFS) Create(name string) (absfs.File, error) {
+	f, err := osFS{}.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return &allocFile{File: f}, nil
+}
+
+type allocFile struct {
+	absfs.File
+	buf []byte
+}
+
+func (f *allocFile) Write(p []byte) (int, error) {
+	f.buf = append(make([]byte, 0, len(f.buf)+len(p)), f.buf...)
+	f.buf = append(f.buf, p...)
+	return len(p), nil
+}
+
+// errorfTB records Errorf calls instead of failing the test.
+type errorfTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *errorfTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertLowAllocWritesFails(t *testing.T) {
+	tb := &errorfTB{TB: t}
+	AssertLowAllocWrites(tb, allocFS{}, 4096, 100, 0.5)
+	if len(tb.errors) != 1 {
+		t.Errorf("AssertLowAllocWrites on a reallocating FileSystem reported %q, want one error", tb.errors)
+	}
+}
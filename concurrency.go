@@ -0,0 +1,151 @@
+package fstesting
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// opErrors collects errors from concurrent workers grouped by operation.
+type opErrors struct {
+	mu   sync.Mutex
+	errs map[string][]error
+}
+
+func (e *opErrors) add(op string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.errs == nil {
+		e.errs = make(map[string][]error)
+	}
+	e.errs[op] = append(e.errs[op], err)
+}
+
+// summary reports the number of failures of each operation along with the
+// first error seen for it. It returns "" if there were no failures.
+func (e *opErrors) summary() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var ops []string
+	for op := range e.errs {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	var list []string
+	for _, op := range ops {
+		list = append(list, fmt.Sprintf("%s: %d failures, first: %s", op, len(e.errs[op]), e.errs[op][0]))
+	}
+	return strings.Join(list, "\n")
+}
+
+// testConcurrency runs s.ConcurrencyLevel workers at once against the
+// FileSystem.
+func (s *Suite) testConcurrency(t *testing.T, testDir string) {
+	s.run(t, "PrivateFiles", func(t *testing.T) {
+		s.testPrivateFiles(t, testDir)
+	})
+	s.run(t, "ExclusiveCreate", func(t *testing.T) {
+		s.testExclusiveCreate(t, testDir)
+	})
+}
+
+// testPrivateFiles runs workers that each create, write random data to, read
+// back and remove their own files. The workers never share a path, so any
+// failure points at unsynchronized state inside the FileSystem.
+func (s *Suite) testPrivateFiles(t *testing.T, testDir string) {
+	const iterations = 20
+	var errs opErrors
+	var wg sync.WaitGroup
+
+	for i := 0; i < s.ConcurrencyLevel; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rng := s.rand(int64(i))
+			for j := 0; j < iterations; j++ {
+				name := path.Join(testDir, fmt.Sprintf("worker%03d-%03d", i, j))
+				data := make([]byte, 1+rng.Intn(8192))
+				rng.Read(data)
+
+				f, err := s.FS.Create(name)
+				if err != nil {
+					errs.add("Create", err)
+					continue
+				}
+				_, err = f.Write(data)
+				if err != nil {
+					errs.add("Write", err)
+				}
+				err = f.Close()
+				if err != nil {
+					errs.add("Close", err)
+				}
+
+				got, err := s.FS.ReadFile(name)
+				if err != nil {
+					errs.add("Read", err)
+				} else if !bytes.Equal(got, data) {
+					errs.add("Read", fmt.Errorf("%q: read %d bytes that differ from the %d written", name, len(got), len(data)))
+				}
+
+				err = s.FS.Remove(name)
+				if err != nil {
+					errs.add("Remove", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if summary := errs.summary(); summary != "" {
+		t.Errorf("concurrent operations failed with %d workers:\n%s", s.ConcurrencyLevel, summary)
+	}
+}
+
+// testExclusiveCreate has every worker try to create the same file with
+// O_EXCL at once, which must succeed for exactly one of them.
+func (s *Suite) testExclusiveCreate(t *testing.T, testDir string) {
+	const rounds = 10
+	for round := 0; round < rounds; round++ {
+		name := path.Join(testDir, fmt.Sprintf("exclusive%03d", round))
+		var errs opErrors
+		var created int32
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+
+		for i := 0; i < s.ConcurrencyLevel; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				f, err := s.FS.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+				if err == nil {
+					atomic.AddInt32(&created, 1)
+					f.Close()
+					return
+				}
+				if !errors.Is(err, fs.ErrExist) {
+					errs.add("OpenFile", err)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		if created != 1 {
+			t.Errorf("%d of %d workers created %q with O_EXCL, want 1", created, s.ConcurrencyLevel, name)
+		}
+		if summary := errs.summary(); summary != "" {
+			t.Errorf("O_EXCL create of %q failed without EEXIST:\n%s", name, summary)
+		}
+	}
+}
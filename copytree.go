@@ -0,0 +1,179 @@
+package fstesting
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/absfs/absfs"
+)
+
+// CopyTree copies the tree rooted at srcRoot in src to dstRoot in dst, which
+// must not already exist. Regular files, directories and, if both
+// FileSystems are absfs.SymLinkers, symbolic links are copied along with
+// their permissions and modification times. Permissions and times are
+// skipped if dst does not implement Chmod or Chtimes.
+func CopyTree(dst, src absfs.FileSystem, dstRoot, srcRoot string) error {
+	info, err := lstat(src, srcRoot)
+	if err != nil {
+		return err
+	}
+	return copyEntry(dst, src, dstRoot, srcRoot, info)
+}
+
+// lstat calls fs.Lstat if fs is a SymLinker and fs.Stat otherwise.
+func lstat(fs absfs.FileSystem, name string) (os.FileInfo, error) {
+	if sl, ok := fs.(absfs.SymLinker); ok {
+		return sl.Lstat(name)
+	}
+	return fs.Stat(name)
+}
+
+func copyEntry(dst, src absfs.FileSystem, dstName, srcName string, info os.FileInfo) error {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		srcLinker, ok1 := src.(absfs.SymLinker)
+		dstLinker, ok2 := dst.(absfs.SymLinker)
+		if !ok1 || !ok2 {
+			return fmt.Errorf("copying symlink %q: %w", srcName, absfs.ErrNotImplemented)
+		}
+		target, err := srcLinker.Readlink(srcName)
+		if err != nil {
+			return err
+		}
+		// Symlink metadata is not copied, few FileSystems support
+		// changing it.
+		return dstLinker.Symlink(target, dstName)
+
+	case info.IsDir():
+		err := dst.Mkdir(dstName, 0700)
+		if err != nil {
+			return err
+		}
+		entries, err := src.ReadDir(srcName)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			info, err := lstat(src, path.Join(srcName, entry.Name()))
+			if err != nil {
+				return err
+			}
+			err = copyEntry(dst, src, path.Join(dstName, entry.Name()), path.Join(srcName, entry.Name()), info)
+			if err != nil {
+				return err
+			}
+		}
+
+	case info.Mode().IsRegular():
+		err := copyFile(dst, src, dstName, srcName)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("copying %q: unsupported file type %s", srcName, info.Mode().Type())
+	}
+
+	err := dst.Chmod(dstName, info.Mode().Perm())
+	if err != nil && !errors.Is(err, absfs.ErrNotImplemented) {
+		return err
+	}
+	err = dst.Chtimes(dstName, info.ModTime(), info.ModTime())
+	if err != nil && !errors.Is(err, absfs.ErrNotImplemented) {
+		return err
+	}
+	return nil
+}
+
+func copyFile(dst, src absfs.FileSystem, dstName, srcName string) error {
+	in, err := src.Open(srcName)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := dst.OpenFile(dstName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// testCopyTree copies trees within s.FS with CopyTree and checks with
+// DiffTree that the copies match the originals.
+func (s *Suite) testCopyTree(t *testing.T, testDir string) {
+	dir := path.Join(testDir, "copytree")
+
+	s.run(t, "Fixture", func(t *testing.T) {
+		src := path.Join(dir, "fixture")
+		spec, err := ParseTreeSpec(`
+			top: top
+			a/one 0640: one
+			a/b/two: two
+			empty/
+		`)
+		if err != nil {
+			t.Fatalf("ParseTreeSpec: %s", err)
+		}
+		spec["a/b/large"] = TreeEntry{Content: string(make([]byte, 100000))}
+		if s.Features.Symlinks {
+			spec["a/link"] = TreeEntry{Target: "one"}
+		}
+		err = BuildTree(s.FS, src, spec)
+		if err != nil {
+			t.Fatalf("BuildTree(%q): %s", src, err)
+		}
+		s.checkCopyTree(t, path.Join(dir, "fixture-copy"), src)
+	})
+	s.run(t, "Random", func(t *testing.T) {
+		src := path.Join(dir, "random")
+		spec, err := GenerateRandomTree(s.FS, src, s.rand(0), TreeParams{})
+		if err != nil {
+			t.Fatalf("GenerateRandomTree(%q): %s", src, err)
+		}
+		for name, entry := range spec {
+			full := path.Join(src, name)
+			if !entry.Dir {
+				s.checkContent(t, full, entry.Content)
+				continue
+			}
+			info, err := s.FS.Stat(full)
+			if err != nil {
+				t.Errorf("Stat(%q): %s", full, err)
+			} else if !info.IsDir() {
+				t.Errorf("%q is %s, want a directory", full, info.Mode())
+			}
+		}
+		s.checkCopyTree(t, path.Join(dir, "random-copy"), src)
+	})
+}
+
+// checkCopyTree copies src to dst and fails the test if DiffTree finds any
+// difference between them.
+func (s *Suite) checkCopyTree(t *testing.T, dst, src string) {
+	t.Helper()
+	err := CopyTree(s.FS, s.FS, dst, src)
+	if err != nil {
+		t.Fatalf("CopyTree(%q, %q): %s", dst, src, err)
+	}
+	diffs, err := DiffTree(s.FS, s.FS, src, dst, DiffOptions{
+		Modes:          s.Features.Permissions,
+		ModTimes:       s.Features.Timestamps,
+		SymlinkTargets: true,
+	})
+	if err != nil {
+		t.Fatalf("DiffTree(%q, %q): %s", src, dst, err)
+	}
+	for _, d := range diffs {
+		t.Errorf("copy differs: %s", d)
+	}
+}
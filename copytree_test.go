@@ -0,0 +1,35 @@
+package fstesting
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/absfs/absfs"
+)
+
+// alternatingFS reverses every other ReadDir listing, so that two listings
+// of identical directories come back in different orders.
+type alternatingFS struct {
+	absfs.FileSystem
+	calls *int
+}
+
+func (a alternatingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := a.FileSystem.ReadDir(name)
+	*a.calls++
+	if *a.calls%2 == 0 {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+	return entries, err
+}
+
+func TestCopyTreeUnsortedReadDir(t *testing.T) {
+	s := &Suite{
+		FS:       alternatingFS{osFS{}, new(int)},
+		TestDir:  t.TempDir(),
+		Features: DefaultFeatures(),
+	}
+	s.RunOnly(t, "CopyTree")
+}
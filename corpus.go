@@ -0,0 +1,179 @@
+package fstesting
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/absfs/absfs"
+)
+
+// SaveTestcases writes cases to w as JSON so that a baseline generated with
+// AutoTest can be replayed later without regenerating it.
+func SaveTestcases(w io.Writer, cases []*Testcase) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cases)
+}
+
+// LoadTestcases reads testcases written by SaveTestcases.
+func LoadTestcases(r io.Reader) ([]*Testcase, error) {
+	var cases []*Testcase
+	err := json.NewDecoder(r).Decode(&cases)
+	if err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
+
+// UnmarshalJSON decodes an ErrorReport written by SaveTestcases. An error
+// interface can not be decoded directly, so Err is rebuilt from the saved
+// fields: *os.PathError, *os.LinkError and syscall.Errno values are restored
+// along with any syscall.Errno they wrap, and all other errors become an
+// *ErrorString carrying the saved message.
+func (e *ErrorReport) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Op       string
+		Path     string
+		Err      json.RawMessage
+		StackStr string
+		TypeStr  string
+		ErrStr   string
+	}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+
+	*e = ErrorReport{
+		Op:       v.Op,
+		Path:     v.Path,
+		Err:      decodeError(v.TypeStr, v.ErrStr, v.Err),
+		StackStr: v.StackStr,
+		TypeStr:  v.TypeStr,
+		ErrStr:   v.ErrStr,
+	}
+	return nil
+}
+
+// decodeError rebuilds an error of type typestr with message errstr from its
+// JSON encoding raw.
+func decodeError(typestr, errstr string, raw json.RawMessage) error {
+	if errstr == "" {
+		return nil
+	}
+
+	switch typestr {
+	case "*fs.PathError", "*os.PathError":
+		var v struct {
+			Op   string
+			Path string
+			Err  json.RawMessage
+		}
+		if json.Unmarshal(raw, &v) == nil {
+			msg := strings.TrimPrefix(errstr, v.Op+" "+v.Path+": ")
+			return &os.PathError{Op: v.Op, Path: v.Path, Err: decodeErrno(v.Err, msg)}
+		}
+
+	case "*os.LinkError":
+		var v struct {
+			Op  string
+			Old string
+			New string
+			Err json.RawMessage
+		}
+		if json.Unmarshal(raw, &v) == nil {
+			msg := strings.TrimPrefix(errstr, v.Op+" "+v.Old+" "+v.New+": ")
+			return &os.LinkError{Op: v.Op, Old: v.Old, New: v.New, Err: decodeErrno(v.Err, msg)}
+		}
+
+	case "syscall.Errno":
+		return decodeErrno(raw, errstr)
+	}
+
+	return &ErrorString{errstr}
+}
+
+// decodeErrno decodes a syscall.Errno from raw, or returns an *ErrorString
+// with message msg if raw does not hold one.
+func decodeErrno(raw json.RawMessage, msg string) error {
+	var errno syscall.Errno
+	if json.Unmarshal(raw, &errno) == nil && errno != 0 {
+		return errno
+	}
+	return &ErrorString{msg}
+}
+
+// OpResult is the outcome of one operation of a replayed Testcase.
+type OpResult struct {
+	Expected error // error recorded in the saved Testcase
+	Actual   error // error returned by the FileSystem under test
+	Diff     error // result of CompareErrors(Expected, Actual)
+}
+
+// TestcaseResult is the outcome of replaying a single Testcase.
+type TestcaseResult struct {
+	Testcase *Testcase
+	Ops      map[string]OpResult
+}
+
+// Passed reports whether every operation matched the saved Testcase.
+func (r *TestcaseResult) Passed() bool {
+	for _, op := range r.Ops {
+		if op.Diff != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// TestcaseResults is the outcome of RunTestcases.
+type TestcaseResults []TestcaseResult
+
+// Mismatches returns the number of mismatched results for each operation,
+// showing where a FileSystem diverges from the saved baseline.
+func (results TestcaseResults) Mismatches() map[string]int {
+	counts := make(map[string]int)
+	for _, r := range results {
+		for name, op := range r.Ops {
+			if op.Diff != nil {
+				counts[name]++
+			}
+		}
+	}
+	return counts
+}
+
+// RunTestcases replays cases against fs in dir and compares the errors
+// returned by fs to the errors recorded in each Testcase. It stops at the
+// first testcase whose precondition can not be established and returns the
+// results so far along with the error.
+func RunTestcases(fs absfs.FileSystem, dir string, cases []*Testcase) (TestcaseResults, error) {
+	var results TestcaseResults
+	for _, testcase := range cases {
+		actual, err := FsTest(fs, dir, testcase)
+		if err != nil {
+			return results, fmt.Errorf("testcase %d: %s", testcase.TestNo, err)
+		}
+
+		ops := make(map[string]OpResult)
+		for name, report := range testcase.Errors {
+			ops[name] = OpResult{Expected: report.Err}
+		}
+		for name, report := range actual.Errors {
+			op := ops[name]
+			op.Actual = report.Err
+			ops[name] = op
+		}
+		for name, op := range ops {
+			op.Diff = CompareErrors(op.Expected, op.Actual)
+			ops[name] = op
+		}
+
+		results = append(results, TestcaseResult{testcase, ops})
+	}
+	return results, nil
+}
@@ -0,0 +1,155 @@
+package fstesting
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestSaveLoadTestcases(t *testing.T) {
+	errs := map[string]error{
+		"PathError": &os.PathError{Op: "open", Path: "/dir/file", Err: syscall.ENOENT},
+		"LinkError": &os.LinkError{Op: "rename", Old: "/dir/old", New: "/dir/new", Err: syscall.EXDEV},
+		"Errno":     syscall.EACCES,
+		"String":    errors.New("plain error"),
+		"Nil":       nil,
+	}
+	saved := &Testcase{
+		TestNo:       7,
+		PreCondition: "created",
+		Op:           "openfile",
+		Path:         "/dir/file",
+		Flags:        os.O_RDWR | os.O_CREATE,
+		Mode:         0640,
+		Errors:       make(map[string]*ErrorReport),
+	}
+	for name, err := range errs {
+		saved.Errors[name] = NewErrorReport(name, "/dir/file", err, "")
+	}
+
+	buf := new(bytes.Buffer)
+	err := SaveTestcases(buf, []*Testcase{saved})
+	if err != nil {
+		t.Fatalf("SaveTestcases: %s", err)
+	}
+	cases, err := LoadTestcases(buf)
+	if err != nil {
+		t.Fatalf("LoadTestcases: %s", err)
+	}
+	if len(cases) != 1 {
+		t.Fatalf("LoadTestcases returned %d testcases, want 1", len(cases))
+	}
+	loaded := cases[0]
+	if loaded.TestNo != saved.TestNo || loaded.PreCondition != saved.PreCondition ||
+		loaded.Op != saved.Op || loaded.Path != saved.Path ||
+		loaded.Flags != saved.Flags || loaded.Mode != saved.Mode {
+		t.Errorf("LoadTestcases = %+v, want %+v", loaded, saved)
+	}
+
+	for name, want := range errs {
+		report, ok := loaded.Errors[name]
+		if !ok {
+			t.Errorf("%s: missing after LoadTestcases", name)
+			continue
+		}
+		if report.Op != name || report.ErrStr != saved.Errors[name].ErrStr || report.TypeStr != saved.Errors[name].TypeStr {
+			t.Errorf("%s: loaded report %+v, want %+v", name, report, saved.Errors[name])
+		}
+		if diff := CompareErrors(want, report.Err); diff != nil {
+			t.Errorf("%s: CompareErrors(saved, loaded): %s", name, diff)
+		}
+	}
+
+	var perr *os.PathError
+	if err := loaded.Errors["PathError"].Err; !errors.As(err, &perr) || !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("PathError loaded as %#v, want *os.PathError wrapping ENOENT", err)
+	}
+	var lerr *os.LinkError
+	if err := loaded.Errors["LinkError"].Err; !errors.As(err, &lerr) || !errors.Is(err, syscall.EXDEV) {
+		t.Errorf("LinkError loaded as %#v, want *os.LinkError wrapping EXDEV", err)
+	}
+	if err := loaded.Errors["Errno"].Err; err != syscall.EACCES {
+		t.Errorf("Errno loaded as %#v, want EACCES", err)
+	}
+	want := &ErrorString{"plain error"}
+	if err := loaded.Errors["String"].Err; !reflect.DeepEqual(err, want) {
+		t.Errorf("String error loaded as %#v, want %#v", err, want)
+	}
+	if err := loaded.Errors["Nil"].Err; err != nil {
+		t.Errorf("nil error loaded as %#v", err)
+	}
+}
+
+// savedTestcases runs every Operation under each precondition against osFS
+// and returns the results as reloaded by LoadTestcases.
+func savedTestcases(t *testing.T) []*Testcase {
+	t.Helper()
+	dir := t.TempDir()
+	var cases []*Testcase
+	testNo := 0
+	for _, op := range Operations {
+		for _, condition := range []string{"notcreated", "created", "dir"} {
+			testNo++
+			testcase, err := FsTest(osFS{}, dir, &Testcase{TestNo: testNo, PreCondition: condition, Op: op.Op})
+			if err != nil {
+				t.Fatalf("FsTest(%s, %s): %s", op.Op, condition, err)
+			}
+			cases = append(cases, testcase)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	err := SaveTestcases(buf, cases)
+	if err != nil {
+		t.Fatalf("SaveTestcases: %s", err)
+	}
+	cases, err = LoadTestcases(buf)
+	if err != nil {
+		t.Fatalf("LoadTestcases: %s", err)
+	}
+	return cases
+}
+
+func TestRunTestcases(t *testing.T) {
+	cases := savedTestcases(t)
+	results, err := RunTestcases(osFS{}, t.TempDir(), cases)
+	if err != nil {
+		t.Fatalf("RunTestcases: %s", err)
+	}
+	if len(results) != len(cases) {
+		t.Fatalf("RunTestcases returned %d results, want %d", len(results), len(cases))
+	}
+	for _, r := range results {
+		if !r.Passed() {
+			t.Errorf("testcase %d (%s, %s) did not pass: %+v", r.Testcase.TestNo, r.Testcase.Op, r.Testcase.PreCondition, r.Ops)
+		}
+	}
+	if m := results.Mismatches(); len(m) != 0 {
+		t.Errorf("Mismatches() = %v, want none", m)
+	}
+}
+
+func TestRunTestcasesMismatch(t *testing.T) {
+	cases := savedTestcases(t)
+	ifs := NewInjectFS(osFS{})
+	ifs.Fail("Remove", "*", syscall.EACCES)
+	results, err := RunTestcases(ifs, t.TempDir(), cases)
+	if err != nil {
+		t.Fatalf("RunTestcases: %s", err)
+	}
+
+	// Remove fails with EACCES where osFS removed the file or returned
+	// ENOENT, so every remove testcase mismatches and nothing else does.
+	want := map[string]int{"Remove": 3}
+	if m := results.Mismatches(); !reflect.DeepEqual(m, want) {
+		t.Errorf("Mismatches() = %v, want %v", m, want)
+	}
+	for _, r := range results {
+		if r.Passed() == (r.Testcase.Op == "remove") {
+			t.Errorf("testcase %d (%s, %s) Passed() = %t", r.Testcase.TestNo, r.Testcase.Op, r.Testcase.PreCondition, r.Passed())
+		}
+	}
+}
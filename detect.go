@@ -0,0 +1,152 @@
+package fstesting
+
+import (
+	"os"
+	"path"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// DetectFeatures probes fs to infer its Features. Probes are run in a
+// temporary directory under fs.TempDir() which is removed afterwards.
+// AtomicRename can not be observed reliably and is never set, and LargeFiles
+// is only probed where SparseFiles is detected. If the probe directory can
+// not be created the zero Features is returned.
+func DetectFeatures(fs absfs.FileSystem) Features {
+	var features Features
+	dir, err := newTestDir(fs, "")
+	if err != nil {
+		return features
+	}
+	defer fs.RemoveAll(dir)
+
+	name := path.Join(dir, "Probe")
+	err = writeAll(fs, name, []byte("probe"))
+	if err != nil {
+		return features
+	}
+
+	if linker, ok := fs.(absfs.SymLinker); ok {
+		link := path.Join(dir, "link")
+		if linker.Symlink(name, link) == nil {
+			target, err := linker.Readlink(link)
+			features.Symlinks = err == nil && target == name
+		}
+	}
+
+	features.Permissions = true
+	for _, mode := range []os.FileMode{0600, 0640} {
+		info, err := chmodStat(fs, name, mode)
+		if err != nil || info.Mode().Perm() != mode {
+			features.Permissions = false
+		}
+	}
+
+	mtime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	if fs.Chtimes(name, mtime, mtime) == nil {
+		info, err := fs.Stat(name)
+		features.Timestamps = err == nil && info.ModTime().Equal(mtime)
+	}
+
+	_, err = fs.Stat(path.Join(dir, "probe"))
+	features.CaseSensitive = os.IsNotExist(err)
+
+	features.SparseFiles = detectSparse(fs, path.Join(dir, "sparse"))
+	// Without sparse files the probe would allocate 2GB, so LargeFiles is
+	// left for the caller to set.
+	features.LargeFiles = features.SparseFiles && detectLarge(fs, path.Join(dir, "large"))
+
+	uid, gid := os.Getuid(), os.Getgid()
+	if uid >= 0 && fs.Chown(name, uid, gid) == nil {
+		info, err := fs.Stat(name)
+		if err == nil {
+			_, ok := sysInt(info, "Uid")
+			features.Ownership = ok
+		}
+	}
+
+	if xfs, ok := fs.(XattrFileSystem); ok {
+		features.ExtendedAttrs = xfs.Setxattr(name, "user.fstesting", []byte("probe")) == nil
+	}
+
+	// Restoring a snapshot affects the whole FileSystem, so support is not
+	// probed beyond the interface check.
+	_, features.Snapshots = fs.(SnapshotFileSystem)
+	_, features.MemoryMapped = fs.(MmapFileSystem)
+
+	if cfs, ok := fs.(CloneFileSystem); ok {
+		features.Reflink = cfs.Clone(name, path.Join(dir, "clone")) == nil
+	}
+
+	if lfs, ok := fs.(LinkFileSystem); ok {
+		link := path.Join(dir, "hardlink")
+		if lfs.Link(name, link) == nil {
+			same, err := SameFile(fs, name, link)
+			features.HardLinks = err == nil && same
+		}
+	}
+
+	if sfs, ok := fs.(SpecialFileSystem); ok {
+		features.SpecialFiles = sfs.Mkfifo(path.Join(dir, "fifo"), 0600) == nil
+	}
+
+	if f, err := fs.Open(name); err == nil {
+		_, features.Locking = f.(LockingFile)
+		f.Close()
+	}
+
+	if sfs, ok := fs.(StatfsFileSystem); ok {
+		_, err := sfs.Statfs(dir)
+		features.SpaceReporting = err == nil
+	}
+
+	return features
+}
+
+// chmodStat changes the mode of name and returns the resulting FileInfo.
+func chmodStat(fs absfs.FileSystem, name string, mode os.FileMode) (os.FileInfo, error) {
+	err := fs.Chmod(name, mode)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(name)
+}
+
+// detectSparse reports whether a hole written in name is left unallocated.
+func detectSparse(fs absfs.FileSystem, name string) bool {
+	const offset = 1 << 20
+	f, err := fs.Create(name)
+	if err != nil {
+		return false
+	}
+	_, err = f.WriteAt([]byte("sparse"), offset)
+	f.Close()
+	if err != nil {
+		return false
+	}
+	info, err := fs.Stat(name)
+	if err != nil {
+		return false
+	}
+	blocks, ok := sysInt(info, "Blocks")
+	return ok && blocks*512 < offset
+}
+
+// detectLarge reports whether name can be grown past 2GB by writing a few
+// bytes beyond that offset. It must only be called where holes are sparse.
+func detectLarge(fs absfs.FileSystem, name string) bool {
+	const offset = 1 << 31
+	data := []byte("large")
+	f, err := fs.Create(name)
+	if err != nil {
+		return false
+	}
+	_, err = f.WriteAt(data, offset)
+	f.Close()
+	if err != nil {
+		return false
+	}
+	info, err := fs.Stat(name)
+	return err == nil && info.Size() == offset+int64(len(data))
+}
@@ -0,0 +1,53 @@
+package fstesting
+
+import "testing"
+
+func TestDetectFeaturesOS(t *testing.T) {
+	got, want := DetectFeatures(osFS{}), OSFeatures()
+	for _, tt := range []struct {
+		name      string
+		got, want bool
+	}{
+		{"Permissions", got.Permissions, want.Permissions},
+		{"Timestamps", got.Timestamps, want.Timestamps},
+		{"CaseSensitive", got.CaseSensitive, want.CaseSensitive},
+		{"Ownership", got.Ownership, want.Ownership},
+		{"HardLinks", got.HardLinks, want.HardLinks},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("DetectFeatures(osFS).%s = %t, OSFeatures has %t", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Symlinks may need privileges on Windows, and whether holes are
+	// sparse depends on the filesystem holding the temporary directory.
+	if want.Symlinks && !got.Symlinks {
+		t.Error("DetectFeatures(osFS).Symlinks = false, OSFeatures has true")
+	}
+	if got.SparseFiles && !got.LargeFiles {
+		t.Error("DetectFeatures(osFS) found sparse files but not large files")
+	}
+	if got.AtomicRename {
+		t.Error("DetectFeatures(osFS).AtomicRename = true, want it never set")
+	}
+}
+
+func TestDetectFeaturesCleanup(t *testing.T) {
+	base := t.TempDir()
+	DetectFeatures(tempDirFS{osFS{}, base})
+	entries, err := osFS{}.ReadDir(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("DetectFeatures left %d entries in %q", len(entries), base)
+	}
+}
+
+// tempDirFS is osFS with its TempDir moved to dir.
+type tempDirFS struct {
+	osFS
+	dir string
+}
+
+func (t tempDirFS) TempDir() string { return t.dir }
@@ -0,0 +1,179 @@
+package fstesting
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/absfs/absfs"
+)
+
+// DiffOptions controls which metadata DiffTree compares. Names, file types,
+// sizes and content are always compared.
+type DiffOptions struct {
+	Modes          bool // permission bits
+	ModTimes       bool // modification times
+	SymlinkTargets bool // symbolic link targets
+}
+
+// DiffKind is the kind of a Difference.
+type DiffKind string
+
+const (
+	OnlyInA        DiffKind = "only in a"
+	OnlyInB        DiffKind = "only in b"
+	TypeDiffers    DiffKind = "type"
+	SizeDiffers    DiffKind = "size"
+	ContentDiffers DiffKind = "content"
+	ModeDiffers    DiffKind = "mode"
+	ModTimeDiffers DiffKind = "modtime"
+	TargetDiffers  DiffKind = "symlink target"
+)
+
+// Difference is one way in which two trees differ. Path is relative to the
+// roots of the trees, "." being the roots themselves, and A and B describe
+// the differing values in each tree.
+type Difference struct {
+	Path string
+	Kind DiffKind
+	A, B string
+}
+
+func (d Difference) String() string {
+	switch d.Kind {
+	case OnlyInA, OnlyInB:
+		return fmt.Sprintf("%s: %s", d.Path, d.Kind)
+	}
+	return fmt.Sprintf("%s: %s differs %s != %s", d.Path, d.Kind, d.A, d.B)
+}
+
+// DiffTree compares the tree rooted at rootA in a with the tree rooted at
+// rootB in b and returns their differences. Directory entries are compared
+// in lexical order. The error is non-nil only if a tree could not be read.
+func DiffTree(a, b absfs.FileSystem, rootA, rootB string, opts DiffOptions) ([]Difference, error) {
+	d := &differ{a: a, b: b, rootA: rootA, rootB: rootB, opts: opts}
+	infoA, err := lstat(a, rootA)
+	if err != nil {
+		return nil, err
+	}
+	infoB, err := lstat(b, rootB)
+	if err != nil {
+		return nil, err
+	}
+	err = d.diff(".", infoA, infoB)
+	return d.list, err
+}
+
+type differ struct {
+	a, b         absfs.FileSystem
+	rootA, rootB string
+	opts         DiffOptions
+	list         []Difference
+}
+
+func (d *differ) add(rel string, kind DiffKind, a, b interface{}) {
+	d.list = append(d.list, Difference{rel, kind, fmt.Sprint(a), fmt.Sprint(b)})
+}
+
+func (d *differ) diff(rel string, infoA, infoB os.FileInfo) error {
+	nameA, nameB := path.Join(d.rootA, rel), path.Join(d.rootB, rel)
+	typeA, typeB := infoA.Mode().Type(), infoB.Mode().Type()
+	if typeA != typeB {
+		d.add(rel, TypeDiffers, typeA, typeB)
+		return nil
+	}
+
+	if typeA&os.ModeSymlink != 0 {
+		if !d.opts.SymlinkTargets {
+			return nil
+		}
+		targetA, err := d.a.(absfs.SymLinker).Readlink(nameA)
+		if err != nil {
+			return err
+		}
+		targetB, err := d.b.(absfs.SymLinker).Readlink(nameB)
+		if err != nil {
+			return err
+		}
+		if targetA != targetB {
+			d.add(rel, TargetDiffers, targetA, targetB)
+		}
+		return nil
+	}
+
+	if d.opts.Modes && infoA.Mode().Perm() != infoB.Mode().Perm() {
+		d.add(rel, ModeDiffers, infoA.Mode().Perm(), infoB.Mode().Perm())
+	}
+	if d.opts.ModTimes && !infoA.ModTime().Equal(infoB.ModTime()) {
+		d.add(rel, ModTimeDiffers, infoA.ModTime(), infoB.ModTime())
+	}
+
+	switch {
+	case infoA.Mode().IsRegular():
+		if infoA.Size() != infoB.Size() {
+			d.add(rel, SizeDiffers, infoA.Size(), infoB.Size())
+			return nil
+		}
+		dataA, err := d.a.ReadFile(nameA)
+		if err != nil {
+			return err
+		}
+		dataB, err := d.b.ReadFile(nameB)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(dataA, dataB) {
+			d.add(rel, ContentDiffers, fmt.Sprintf("%d bytes", len(dataA)), fmt.Sprintf("%d bytes", len(dataB)))
+		}
+
+	case infoA.IsDir():
+		return d.diffDir(rel, nameA, nameB)
+	}
+	return nil
+}
+
+// diffDir compares the entries of the directories nameA and nameB.
+func (d *differ) diffDir(rel, nameA, nameB string) error {
+	entriesA, err := d.a.ReadDir(nameA)
+	if err != nil {
+		return err
+	}
+	entriesB, err := d.b.ReadDir(nameB)
+	if err != nil {
+		return err
+	}
+
+	// Not every FileSystem sorts ReadDir, so sort both lists to merge them.
+	sort.Slice(entriesA, func(i, j int) bool { return entriesA[i].Name() < entriesA[j].Name() })
+	sort.Slice(entriesB, func(i, j int) bool { return entriesB[i].Name() < entriesB[j].Name() })
+	i, j := 0, 0
+	for i < len(entriesA) || j < len(entriesB) {
+		switch {
+		case j == len(entriesB) || i < len(entriesA) && entriesA[i].Name() < entriesB[j].Name():
+			d.add(path.Join(rel, entriesA[i].Name()), OnlyInA, "", "")
+			i++
+		case i == len(entriesA) || entriesB[j].Name() < entriesA[i].Name():
+			d.add(path.Join(rel, entriesB[j].Name()), OnlyInB, "", "")
+			j++
+		default:
+			name := entriesA[i].Name()
+			infoA, err := lstat(d.a, path.Join(nameA, name))
+			if err != nil {
+				return err
+			}
+			infoB, err := lstat(d.b, path.Join(nameB, name))
+			if err != nil {
+				return err
+			}
+			err = d.diff(path.Join(rel, name), infoA, infoB)
+			if err != nil {
+				return err
+			}
+			i++
+			j++
+		}
+	}
+	return nil
+}
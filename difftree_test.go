@@ -0,0 +1,64 @@
+package fstesting
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/absfs/absfs"
+)
+
+// unsortedFS returns ReadDir entries in reverse order, as FileSystems
+// without Features.SortedReadDir may.
+type unsortedFS struct {
+	absfs.FileSystem
+}
+
+func (u unsortedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := u.FileSystem.ReadDir(name)
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, err
+}
+
+func TestDiffTreeUnsortedReadDir(t *testing.T) {
+	spec, err := ParseTreeSpec(`
+		x: x
+		y: y
+		d/
+		d/a: a
+		d/b: b
+	`)
+	if err != nil {
+		t.Fatalf("ParseTreeSpec: %s", err)
+	}
+	a, b := t.TempDir(), t.TempDir()
+	for _, root := range []string{a, b} {
+		err := BuildTree(osFS{}, root, spec)
+		if err != nil {
+			t.Fatalf("BuildTree(%q): %s", root, err)
+		}
+	}
+
+	// Only b is unsorted, so the two listings come back in different orders.
+	fsys := unsortedFS{osFS{}}
+	diffs, err := DiffTree(osFS{}, fsys, a, b, DiffOptions{})
+	if err != nil {
+		t.Fatalf("DiffTree: %s", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("DiffTree of identical trees = %v, want no differences", diffs)
+	}
+
+	err = osFS{}.Remove(b + "/d/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err = DiffTree(osFS{}, fsys, a, b, DiffOptions{})
+	if err != nil {
+		t.Fatalf("DiffTree: %s", err)
+	}
+	if len(diffs) != 1 || diffs[0].Path != "d/a" || diffs[0].Kind != OnlyInA {
+		t.Errorf("DiffTree after removing d/a from b = %v, want d/a only in a", diffs)
+	}
+}
@@ -0,0 +1,519 @@
+package fstesting
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// testDirectoryOperations covers creating and listing directories.
+func (s *Suite) testDirectoryOperations(t *testing.T, testDir string) {
+	dir := path.Join(testDir, "dirops")
+	s.mkdir(t, dir)
+
+	s.run(t, "EmptyReadDir", func(t *testing.T) {
+		empty := path.Join(dir, "empty")
+		s.mkdir(t, empty)
+
+		entries, err := s.FS.ReadDir(empty)
+		if err != nil {
+			t.Errorf("ReadDir(%q): %s", empty, err)
+		}
+		if entries == nil || len(entries) != 0 {
+			t.Errorf("ReadDir(%q) = %v, want empty non-nil slice", empty, entries)
+		}
+
+		f, err := s.FS.Open(empty)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", empty, err)
+		}
+		defer f.Close()
+		entries, err = f.ReadDir(-1)
+		if err != nil {
+			t.Errorf("File.ReadDir(-1): %s", err)
+		}
+		if entries == nil || len(entries) != 0 {
+			t.Errorf("File.ReadDir(-1) = %v, want empty non-nil slice", entries)
+		}
+
+		f2, err := s.FS.Open(empty)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", empty, err)
+		}
+		defer f2.Close()
+		entries, err = f2.ReadDir(1)
+		if len(entries) != 0 || err != io.EOF {
+			t.Errorf("File.ReadDir(1) = %v, %v, want no entries and io.EOF", entries, err)
+		}
+	})
+	s.run(t, "ReadDirPagination", func(t *testing.T) {
+		paged := path.Join(dir, "paged")
+		var want []string
+		for i := 0; i < 10; i++ {
+			want = append(want, fmt.Sprintf("entry%02d", i))
+		}
+		s.populate(t, paged, want, nil)
+
+		f, err := s.FS.Open(paged)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", paged, err)
+		}
+		defer f.Close()
+
+		seen := make(map[string]bool)
+		for calls := 0; ; calls++ {
+			if calls > len(want) {
+				t.Fatalf("File.ReadDir(3) did not return io.EOF after %d calls", calls)
+			}
+			entries, err := f.ReadDir(3)
+			if len(entries) > 3 {
+				t.Errorf("File.ReadDir(3) returned %d entries", len(entries))
+			}
+			for _, e := range entries {
+				if seen[e.Name()] {
+					t.Errorf("File.ReadDir(3) returned %q twice", e.Name())
+				}
+				seen[e.Name()] = true
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("File.ReadDir(3): %s", err)
+			}
+		}
+
+		for _, name := range want {
+			if !seen[name] {
+				t.Errorf("File.ReadDir(3) never returned %q", name)
+			}
+		}
+		if len(seen) != len(want) {
+			t.Errorf("File.ReadDir(3) returned %d entries, want %d", len(seen), len(want))
+		}
+	})
+	s.run(t, "ReaddirConsistency", func(t *testing.T) {
+		listed := path.Join(dir, "listed")
+		s.populate(t, listed, []string{"a", "b", "c"}, []string{"d", "e"})
+
+		f, err := s.FS.Open(listed)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", listed, err)
+		}
+		infos, err := f.Readdir(-1)
+		f.Close()
+		if err != nil {
+			t.Fatalf("File.Readdir(-1): %s", err)
+		}
+
+		f, err = s.FS.Open(listed)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", listed, err)
+		}
+		entries, err := f.ReadDir(-1)
+		f.Close()
+		if err != nil {
+			t.Fatalf("File.ReadDir(-1): %s", err)
+		}
+
+		if len(infos) != len(entries) {
+			t.Errorf("Readdir returned %d entries, ReadDir returned %d", len(infos), len(entries))
+		}
+		byName := make(map[string]os.FileInfo)
+		for _, info := range infos {
+			byName[info.Name()] = info
+		}
+		for _, e := range entries {
+			info, ok := byName[e.Name()]
+			if !ok {
+				t.Errorf("ReadDir returned %q which Readdir did not", e.Name())
+				continue
+			}
+			if e.IsDir() != info.IsDir() {
+				t.Errorf("%q: DirEntry.IsDir() = %t, FileInfo.IsDir() = %t", e.Name(), e.IsDir(), info.IsDir())
+			}
+			einfo, err := e.Info()
+			if err != nil {
+				t.Errorf("%q: DirEntry.Info(): %s", e.Name(), err)
+				continue
+			}
+			if err := compareInfo(einfo, info); err != nil {
+				t.Errorf("%q: DirEntry.Info() and Readdir differ: %s", e.Name(), err)
+			}
+		}
+	})
+	s.run(t, "Readdirnames", func(t *testing.T) {
+		named := path.Join(dir, "named")
+		want := []string{"a", "b", "c", "d", "e"}
+		s.populate(t, named, want[:3], want[3:])
+
+		f, err := s.FS.Open(named)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", named, err)
+		}
+		names, err := f.Readdirnames(-1)
+		f.Close()
+		if errors.Is(err, absfs.ErrNotImplemented) {
+			s.skip(t, "Readdirnames not implemented")
+		}
+		if err != nil {
+			t.Fatalf("File.Readdirnames(-1): %s", err)
+		}
+		sort.Strings(names)
+		if strings.Join(names, ",") != strings.Join(want, ",") {
+			t.Errorf("File.Readdirnames(-1) = %q, want %q", names, want)
+		}
+
+		f, err = s.FS.Open(named)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", named, err)
+		}
+		defer f.Close()
+		names = nil
+		for calls := 0; ; calls++ {
+			if calls > len(want) {
+				t.Fatalf("File.Readdirnames(2) did not return io.EOF after %d calls", calls)
+			}
+			batch, err := f.Readdirnames(2)
+			if len(batch) > 2 {
+				t.Errorf("File.Readdirnames(2) returned %d names", len(batch))
+			}
+			names = append(names, batch...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("File.Readdirnames(2): %s", err)
+			}
+		}
+		sort.Strings(names)
+		if strings.Join(names, ",") != strings.Join(want, ",") {
+			t.Errorf("File.Readdirnames(2) returned %q, want %q", names, want)
+		}
+	})
+	s.run(t, "ReadDirDuringMutation", func(t *testing.T) {
+		mutated := path.Join(dir, "mutated")
+		var want []string
+		for i := 0; i < 10; i++ {
+			want = append(want, fmt.Sprintf("entry%02d", i))
+		}
+		s.populate(t, mutated, want, nil)
+
+		f, err := s.FS.Open(mutated)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", mutated, err)
+		}
+		defer f.Close()
+		entries, err := f.ReadDir(3)
+		if err != nil {
+			t.Fatalf("File.ReadDir(3): %s", err)
+		}
+
+		seen := make(map[string]bool)
+		for _, e := range entries {
+			seen[e.Name()] = true
+		}
+		removed := make(map[string]bool)
+		for _, name := range want {
+			if !seen[name] && len(removed) < 2 {
+				removed[name] = true
+				err := s.FS.Remove(path.Join(mutated, name))
+				if err != nil {
+					t.Fatalf("Remove(%q): %s", name, err)
+				}
+			}
+		}
+		added := map[string]bool{"added0": true, "added1": true}
+		for name := range added {
+			s.writeFile(t, path.Join(mutated, name), []byte(name))
+		}
+
+		rest, err := f.ReadDir(-1)
+		if err != nil {
+			t.Fatalf("File.ReadDir(-1): %s", err)
+		}
+		// POSIX leaves it unspecified whether entries created or removed
+		// after the first read are listed, and buffering makes real
+		// filesystems mix the two. ReadDirSnapshot FileSystems must list
+		// exactly the original entries, others only the original and
+		// created entries, each at most once.
+		snapshot := s.Features.ReadDirSnapshot
+		for _, e := range rest {
+			name := e.Name()
+			switch {
+			case seen[name]:
+				t.Errorf("File.ReadDir returned %q twice", name)
+			case added[name] && snapshot:
+				t.Errorf("File.ReadDir returned %q created after the first read", name)
+			case !added[name] && !strings.HasPrefix(name, "entry"):
+				t.Errorf("File.ReadDir returned unexpected entry %q", name)
+			}
+			seen[name] = true
+		}
+		for _, name := range want {
+			if !seen[name] && (snapshot || !removed[name]) {
+				t.Errorf("File.ReadDir never returned %q", name)
+			}
+		}
+	})
+	s.run(t, "HiddenFiles", func(t *testing.T) {
+		hidden := path.Join(dir, "hidden")
+		want := []string{".hidden", "visible.txt"}
+		s.populate(t, hidden, want, nil)
+
+		entries, err := s.FS.ReadDir(hidden)
+		if err != nil {
+			t.Fatalf("ReadDir(%q): %s", hidden, err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		sort.Strings(names)
+		if strings.Join(names, ",") != strings.Join(want, ",") {
+			t.Errorf("ReadDir(%q) = %q, want %q", hidden, names, want)
+		}
+
+		// Unlike shells, Go's Glob does not treat dot files specially.
+		fsys, err := IOFS(s.FS, hidden)
+		if err != nil {
+			t.Fatalf("IOFS(%q): %s", hidden, err)
+		}
+		matches, err := fs.Glob(fsys, "*")
+		if err != nil {
+			t.Fatalf("Glob(%q): %s", "*", err)
+		}
+		if strings.Join(matches, ",") != strings.Join(want, ",") {
+			t.Errorf("Glob(%q) = %q, want %q", "*", matches, want)
+		}
+	})
+	s.run(t, "ReadDirOrder", func(t *testing.T) {
+		ordered := path.Join(dir, "ordered")
+		created := []string{"m", "b", "z", "a", "k", "c"}
+		s.populate(t, ordered, created[:3], created[3:])
+
+		entries, err := s.FS.ReadDir(ordered)
+		if err != nil {
+			t.Fatalf("ReadDir(%q): %s", ordered, err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		if s.Features.SortedReadDir && !sort.StringsAreSorted(names) {
+			t.Errorf("ReadDir(%q) = %q, want entries sorted by name", ordered, names)
+		}
+
+		// Without SortedReadDir any order is allowed, but not omissions.
+		sort.Strings(names)
+		want := append([]string(nil), created...)
+		sort.Strings(want)
+		if strings.Join(names, ",") != strings.Join(want, ",") {
+			t.Errorf("ReadDir(%q) returned %q, want %q", ordered, names, want)
+		}
+	})
+	s.run(t, "LargeDir", func(t *testing.T) {
+		if testing.Short() {
+			s.skip(t, "skipping large directory test in short mode")
+		}
+		n := s.LargeDirCount
+		if n <= 0 {
+			n = 10000
+		}
+		large := path.Join(dir, "large")
+		s.mkdir(t, large)
+
+		// Time the first and last tenth of the creates. A FileSystem that
+		// scans the directory on every create is quadratic, making the last
+		// creates up to nineteen times slower than the first. Timings are
+		// noisy, so only a slowdown of over ten times fails, and only once
+		// the first tenth is long enough for timer resolution not to matter.
+		tenth := n / 10
+		var first, last time.Duration
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			switch i {
+			case tenth:
+				first = time.Since(start)
+			case n - tenth:
+				start = time.Now()
+			}
+			name := path.Join(large, fmt.Sprintf("entry%06d", i))
+			f, err := s.FS.Create(name)
+			if err != nil {
+				t.Fatalf("Create(%q): %s", name, err)
+			}
+			f.Close()
+		}
+		last = time.Since(start)
+		t.Logf("created %d entries, first tenth took %s, last tenth %s", n, first, last)
+		const minFirst, maxSlowdown = time.Millisecond, 10
+		if first >= minFirst && last > maxSlowdown*first {
+			t.Errorf("last tenth of creates took %.1f times as long as the first, want at most %d", float64(last)/float64(first), maxSlowdown)
+		}
+
+		entries, err := s.FS.ReadDir(large)
+		if err != nil {
+			t.Fatalf("ReadDir(%q): %s", large, err)
+		}
+		if len(entries) != n {
+			t.Errorf("ReadDir(%q) returned %d entries, want %d", large, len(entries), n)
+		}
+
+		f, err := s.FS.Open(large)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", large, err)
+		}
+		defer f.Close()
+
+		const page = 100
+		seen := make(map[string]bool, n)
+		for calls := 0; ; calls++ {
+			if calls > n/page+1 {
+				t.Fatalf("File.ReadDir(%d) did not return io.EOF after %d calls", page, calls)
+			}
+			entries, err := f.ReadDir(page)
+			if len(entries) > page {
+				t.Errorf("File.ReadDir(%d) returned %d entries", page, len(entries))
+			}
+			for _, e := range entries {
+				if seen[e.Name()] {
+					t.Errorf("File.ReadDir(%d) returned %q twice", page, e.Name())
+				}
+				seen[e.Name()] = true
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("File.ReadDir(%d): %s", page, err)
+			}
+		}
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("entry%06d", i)
+			if !seen[name] {
+				t.Errorf("File.ReadDir(%d) never returned %q", page, name)
+			}
+		}
+	})
+	s.run(t, "DirEntryType", func(t *testing.T) {
+		typed := path.Join(dir, "typed")
+		spec := TreeSpec{"file": {Content: "file"}, "dir": {Dir: true}}
+		want := map[string]fs.FileMode{"file": 0, "dir": fs.ModeDir}
+		if s.Features.Symlinks {
+			spec["link"] = TreeEntry{Target: "file"}
+			want["link"] = fs.ModeSymlink
+		}
+		err := BuildTree(s.FS, typed, spec)
+		if err != nil {
+			t.Fatalf("BuildTree(%q): %s", typed, err)
+		}
+
+		entries, err := s.FS.ReadDir(typed)
+		if err != nil {
+			t.Fatalf("ReadDir(%q): %s", typed, err)
+		}
+		f, err := s.FS.Open(typed)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", typed, err)
+		}
+		defer f.Close()
+		fileEntries, err := f.ReadDir(-1)
+		if err != nil {
+			t.Fatalf("File.ReadDir(-1): %s", err)
+		}
+
+		for _, list := range []struct {
+			op      string
+			entries []fs.DirEntry
+		}{{"ReadDir", entries}, {"File.ReadDir", fileEntries}} {
+			op, entries := list.op, list.entries
+			if len(entries) != len(want) {
+				t.Errorf("%s(%q) returned %d entries, want %d", op, typed, len(entries), len(want))
+			}
+			for _, e := range entries {
+				typ, ok := want[e.Name()]
+				if !ok {
+					t.Errorf("%s(%q) returned unexpected entry %q", op, typed, e.Name())
+					continue
+				}
+				if e.Type() != typ {
+					t.Errorf("%s: %q Type() = %s, want %s", op, e.Name(), e.Type(), typ)
+				}
+				if e.IsDir() != (typ == fs.ModeDir) {
+					t.Errorf("%s: %q IsDir() = %t, want %t", op, e.Name(), e.IsDir(), typ == fs.ModeDir)
+				}
+			}
+		}
+	})
+	s.run(t, "OpenDirectoryFlag", func(t *testing.T) {
+		odir := path.Join(dir, "odir")
+		file := path.Join(odir, "file")
+		s.populate(t, odir, []string{"file"}, nil)
+
+		for _, flag := range []int{os.O_WRONLY, os.O_RDWR} {
+			f, err := s.FS.OpenFile(odir, flag, 0)
+			if err == nil {
+				f.Close()
+				t.Errorf("OpenFile(%q, %#o) of a directory succeeded", odir, flag)
+			} else {
+				checkPathError(t, "OpenFile", err, syscall.EISDIR)
+			}
+		}
+
+		if oDirectory == 0 {
+			s.skip(t, "O_DIRECTORY is not defined on this platform")
+		}
+		f, err := s.FS.OpenFile(odir, os.O_RDONLY|oDirectory, 0)
+		if err != nil {
+			t.Fatalf("OpenFile(%q, O_DIRECTORY): %s", odir, err)
+		}
+		f.Close()
+
+		f, err = s.FS.OpenFile(file, os.O_RDONLY|oDirectory, 0)
+		if err == nil {
+			f.Close()
+			t.Errorf("OpenFile(%q, O_DIRECTORY) of a regular file succeeded", file)
+		} else {
+			checkPathError(t, "OpenFile", err, syscall.ENOTDIR)
+		}
+	})
+}
+
+// populate creates dir containing the given files and subdirectories.
+func (s *Suite) populate(t *testing.T, dir string, files, dirs []string) {
+	t.Helper()
+	s.mkdir(t, dir)
+	for _, name := range files {
+		s.writeFile(t, path.Join(dir, name), []byte(name))
+	}
+	for _, name := range dirs {
+		s.mkdir(t, path.Join(dir, name))
+	}
+}
+
+// compareInfo returns an error describing the first difference in name, size,
+// mode or modification time between a and b, or nil if there is none.
+func compareInfo(a, b os.FileInfo) error {
+	switch {
+	case a.Name() != b.Name():
+		return fmt.Errorf("names differ %q != %q", a.Name(), b.Name())
+	case a.Mode() != b.Mode():
+		return fmt.Errorf("modes differ %s != %s", a.Mode(), b.Mode())
+	case !a.IsDir() && a.Size() != b.Size():
+		return fmt.Errorf("sizes differ %d != %d", a.Size(), b.Size())
+	case !a.ModTime().Equal(b.ModTime()):
+		return fmt.Errorf("modification times differ %s != %s", a.ModTime(), b.ModTime())
+	}
+	return nil
+}
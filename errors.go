@@ -0,0 +1,113 @@
+package fstesting
+
+import (
+	"errors"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+)
+
+// testErrorSemantics checks that operations with well defined failure (or
+// non-failure) modes behave like their os package counterparts.
+func (s *Suite) testErrorSemantics(t *testing.T, testDir string) {
+	dir := path.Join(testDir, "errors")
+	s.mkdir(t, dir)
+
+	s.run(t, "RemoveAllMissing", func(t *testing.T) {
+		name := path.Join(dir, "never_existed")
+		err := s.FS.RemoveAll(name)
+		if err != nil {
+			t.Errorf("RemoveAll(%q) = %s, want nil", name, err)
+		}
+	})
+
+	s.run(t, "RemoveAllFile", func(t *testing.T) {
+		name := path.Join(dir, "removeall_file")
+		s.writeFile(t, name, []byte("file"))
+
+		err := s.FS.RemoveAll(name)
+		if err != nil {
+			t.Fatalf("RemoveAll(%q): %s", name, err)
+		}
+		_, err = s.FS.Stat(name)
+		if !os.IsNotExist(err) {
+			t.Errorf("Stat(%q) after RemoveAll error = %v, want not exist", name, err)
+		}
+	})
+	s.run(t, "RemoveNonEmptyDir", func(t *testing.T) {
+		sub := path.Join(dir, "remove_nonempty")
+		s.populate(t, sub, []string{"occupant"}, nil)
+
+		err := s.FS.Remove(sub)
+		if err == nil {
+			t.Fatalf("Remove(%q) of a non-empty directory succeeded", sub)
+		}
+		// POSIX allows either ENOTEMPTY or EEXIST.
+		if !errors.Is(err, syscall.ENOTEMPTY) && !errors.Is(err, syscall.EEXIST) {
+			t.Errorf("Remove(%q) error = %s, want ENOTEMPTY or EEXIST", sub, err)
+		}
+
+		occupant := path.Join(sub, "occupant")
+		err = s.FS.Remove(occupant)
+		if err != nil {
+			t.Fatalf("Remove(%q): %s", occupant, err)
+		}
+		err = s.FS.Remove(sub)
+		if err != nil {
+			t.Errorf("Remove(%q) of an emptied directory: %s", sub, err)
+		}
+	})
+	s.run(t, "OpenModeEnforced", func(t *testing.T) {
+		name := path.Join(dir, "openmode")
+		s.writeFile(t, name, []byte("content"))
+
+		f, err := s.FS.OpenFile(name, os.O_RDONLY, 0)
+		if err != nil {
+			t.Fatalf("OpenFile(%q, O_RDONLY): %s", name, err)
+		}
+		n, err := f.Write([]byte("x"))
+		f.Close()
+		if err == nil || n != 0 {
+			t.Errorf("Write to O_RDONLY file = %d, %v, want 0 and an error", n, err)
+		} else {
+			checkPathError(t, "Write to O_RDONLY file", err, syscall.EBADF, syscall.EPERM, syscall.EACCES)
+		}
+
+		f, err = s.FS.OpenFile(name, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatalf("OpenFile(%q, O_WRONLY): %s", name, err)
+		}
+		n, err = f.Read(make([]byte, 4))
+		f.Close()
+		if err == nil || n != 0 {
+			t.Errorf("Read from O_WRONLY file = %d, %v, want 0 and an error", n, err)
+		} else {
+			checkPathError(t, "Read from O_WRONLY file", err, syscall.EBADF, syscall.EPERM, syscall.EACCES)
+		}
+		s.checkContent(t, name, "content")
+	})
+	s.run(t, "ReadDirectoryAsFile", func(t *testing.T) {
+		sub := path.Join(dir, "readdir_as_file")
+		s.mkdir(t, sub)
+
+		data, err := s.FS.ReadFile(sub)
+		if err == nil {
+			t.Errorf("ReadFile(%q) of a directory = %q, want error", sub, data)
+		} else {
+			checkPathError(t, "ReadFile of a directory", err, syscall.EISDIR)
+		}
+
+		f, err := s.FS.Open(sub)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", sub, err)
+		}
+		defer f.Close()
+		n, err := f.Read(make([]byte, 16))
+		if err == nil || n != 0 {
+			t.Errorf("Read of a directory = %d, %v, want 0 and an error", n, err)
+		} else {
+			checkPathError(t, "Read of a directory", err, syscall.EISDIR)
+		}
+	})
+}
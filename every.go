@@ -10,6 +10,8 @@ import (
 	// fstesting "github.com/absfs/testing"
 )
 
+// ForEveryFlag calls fn with each flag returned by EveryFlag in order. It
+// stops at and returns the first error returned by fn.
 func ForEveryFlag(fn func(flag int) error) error {
 	for _, flag := range EveryFlag() {
 		err := fn(flag)
@@ -20,6 +22,8 @@ func ForEveryFlag(fn func(flag int) error) error {
 	return nil
 }
 
+// ForEveryPermission calls fn with each mode returned by EveryPermission in
+// order. It stops at and returns the first error returned by fn.
 func ForEveryPermission(fn func(mode os.FileMode) error) error {
 	for _, mode := range EveryPermission() {
 		err := fn(mode)
@@ -30,6 +34,8 @@ func ForEveryPermission(fn func(mode os.FileMode) error) error {
 	return nil
 }
 
+// EveryPermission returns all 512 combinations of the read, write and execute
+// bits for user, group and other, sorted in ascending order.
 func EveryPermission() []os.FileMode {
 	ints := make(sort.IntSlice, 512)
 	perms := []uint{absfs.OS_READ, absfs.OS_WRITE, absfs.OS_EX}
@@ -59,15 +65,18 @@ func EveryPermission() []os.FileMode {
 	return modes
 }
 
+// EveryFlag returns every combination of an access mode (O_RDONLY, O_WRONLY or
+// O_RDWR) with any subset of O_APPEND, O_CREATE, O_EXCL, O_SYNC and O_TRUNC,
+// sorted in ascending order. There are 96 such flags.
 func EveryFlag() []int {
 	flagList := []int{0, os.O_APPEND, os.O_CREATE, os.O_EXCL, os.O_SYNC, os.O_TRUNC}
 	accessList := []int{0, os.O_WRONLY, os.O_RDWR, os.O_RDONLY}
 	var flags sort.IntSlice
 
 	for _, acc := range accessList {
-		flag := acc
+		for i := 0; i < 64; i++ {
+			flag := acc
 
-		for i := 0; i < 63; i++ {
 			if 1<<0&i != 0 {
 				flag |= flagList[5]
 			}
@@ -0,0 +1,55 @@
+package fstesting
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEveryPermission(t *testing.T) {
+	modes := EveryPermission()
+	if len(modes) != 512 {
+		t.Fatalf("EveryPermission returned %d modes, want 512", len(modes))
+	}
+	seen := make(map[os.FileMode]bool)
+	for i, mode := range modes {
+		if mode&^os.ModePerm != 0 {
+			t.Errorf("mode %s has bits outside os.ModePerm", mode)
+		}
+		if seen[mode] {
+			t.Errorf("mode %s returned twice", mode)
+		}
+		seen[mode] = true
+		if i > 0 && modes[i-1] >= mode {
+			t.Errorf("modes not sorted: %s before %s", modes[i-1], mode)
+		}
+	}
+}
+
+func TestEveryFlag(t *testing.T) {
+	flags := EveryFlag()
+	if len(flags) != 96 {
+		t.Fatalf("EveryFlag returned %d flags, want 96", len(flags))
+	}
+	const modifiers = os.O_APPEND | os.O_CREATE | os.O_EXCL | os.O_SYNC | os.O_TRUNC
+	access := make(map[int]int)
+	seen := make(map[int]bool)
+	for i, flag := range flags {
+		if seen[flag] {
+			t.Errorf("flag %#x returned twice", flag)
+		}
+		seen[flag] = true
+		if i > 0 && flags[i-1] >= flag {
+			t.Errorf("flags not sorted: %#x before %#x", flags[i-1], flag)
+		}
+		acc := flag &^ modifiers
+		if acc != os.O_RDONLY && acc != os.O_WRONLY && acc != os.O_RDWR {
+			t.Errorf("flag %#x has access mode %#x", flag, acc)
+		}
+		access[acc]++
+	}
+	for _, acc := range []int{os.O_RDONLY, os.O_WRONLY, os.O_RDWR} {
+		if access[acc] != 32 {
+			t.Errorf("%d flags with access mode %#x, want 32", access[acc], acc)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package fstesting
+
+import (
+	"os"
+
+	"github.com/absfs/absfs"
+)
+
+// FaultyOptions selects the faults injected by a FaultyFS.
+type FaultyOptions struct {
+	// MaxChunk is the largest number of bytes a single read or write will
+	// transfer. Values less than 1 are treated as 1.
+	MaxChunk int
+
+	// ShortReads limits Read and ReadAt to MaxChunk bytes per call.
+	ShortReads bool
+
+	// ShortWrites limits Write, WriteAt and WriteString to MaxChunk bytes per
+	// call.
+	ShortWrites bool
+}
+
+// FaultyFS wraps a FileSystem so that files opened through it perform short
+// reads and writes. Short transfers are reported with a nil error, so code
+// that assumes a single call moves the whole buffer will lose data. Short
+// writes without an error break the io.Writer contract on purpose; they model
+// backends that get this wrong.
+type FaultyFS struct {
+	absfs.FileSystem
+	opts FaultyOptions
+}
+
+// NewFaultyFS returns a FaultyFS that injects the faults in opts into files
+// opened from base.
+func NewFaultyFS(base absfs.FileSystem, opts FaultyOptions) *FaultyFS {
+	if opts.MaxChunk < 1 {
+		opts.MaxChunk = 1
+	}
+	return &FaultyFS{base, opts}
+}
+
+func (fs *FaultyFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	f, err := fs.FileSystem.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyFile{f, fs.opts}, nil
+}
+
+func (fs *FaultyFS) Open(name string) (absfs.File, error) {
+	return fs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (fs *FaultyFS) Create(name string) (absfs.File, error) {
+	return fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+type faultyFile struct {
+	absfs.File
+	opts FaultyOptions
+}
+
+func (f *faultyFile) limit(p []byte, enabled bool) []byte {
+	if enabled && len(p) > f.opts.MaxChunk {
+		return p[:f.opts.MaxChunk]
+	}
+	return p
+}
+
+func (f *faultyFile) Read(p []byte) (int, error) {
+	return f.File.Read(f.limit(p, f.opts.ShortReads))
+}
+
+func (f *faultyFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.File.ReadAt(f.limit(p, f.opts.ShortReads), off)
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	return f.File.Write(f.limit(p, f.opts.ShortWrites))
+}
+
+func (f *faultyFile) WriteAt(p []byte, off int64) (int, error) {
+	return f.File.WriteAt(f.limit(p, f.opts.ShortWrites), off)
+}
+
+func (f *faultyFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
@@ -0,0 +1,125 @@
+package fstesting
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const faultyData = "0123456789abcdef"
+
+func TestFaultyFSShortReads(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "short")
+	err := os.WriteFile(name, []byte(faultyData), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFaultyFS(osFS{}, FaultyOptions{MaxChunk: 4, ShortReads: true}).Open(name)
+	if err != nil {
+		t.Fatalf("Open(%q): %s", name, err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, len(faultyData))
+	n, err := f.Read(buf)
+	if n != 4 || err != nil || string(buf[:n]) != faultyData[:4] {
+		t.Errorf("Read = %d, %v, %q, want 4, nil, %q", n, err, buf[:n], faultyData[:4])
+	}
+	n, err = f.ReadAt(buf, 8)
+	if n != 4 || err != nil || string(buf[:n]) != faultyData[8:12] {
+		t.Errorf("ReadAt(8) = %d, %v, %q, want 4, nil, %q", n, err, buf[:n], faultyData[8:12])
+	}
+}
+
+func TestFaultyFSShortWrites(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "short")
+	f, err := NewFaultyFS(osFS{}, FaultyOptions{MaxChunk: 4, ShortWrites: true}).Create(name)
+	if err != nil {
+		t.Fatalf("Create(%q): %s", name, err)
+	}
+	defer f.Close()
+
+	for _, tt := range []struct {
+		op    string
+		write func() (int, error)
+	}{
+		{"Write", func() (int, error) { return f.Write([]byte(faultyData)) }},
+		{"WriteString", func() (int, error) { return f.WriteString(faultyData) }},
+		{"WriteAt", func() (int, error) { return f.WriteAt([]byte(faultyData), 8) }},
+	} {
+		n, err := tt.write()
+		if n != 4 || err != nil {
+			t.Errorf("%s = %d, %v, want 4, nil", tt.op, n, err)
+		}
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "012301230123" {
+		t.Errorf("file holds %q, want %q", data, "012301230123")
+	}
+}
+
+func TestFaultyFSPassthrough(t *testing.T) {
+	for _, opts := range []FaultyOptions{
+		{MaxChunk: 4},
+		{MaxChunk: 4, ShortReads: true},
+		{MaxChunk: 4, ShortWrites: true},
+	} {
+		name := filepath.Join(t.TempDir(), "full")
+		f, err := NewFaultyFS(osFS{}, opts).OpenFile(name, os.O_RDWR|os.O_CREATE, 0666)
+		if err != nil {
+			t.Fatalf("OpenFile(%q): %s", name, err)
+		}
+		defer f.Close()
+
+		// Transfers are only shortened by the faults that are enabled.
+		if !opts.ShortWrites {
+			n, err := f.Write([]byte(faultyData))
+			if n != len(faultyData) || err != nil {
+				t.Errorf("%+v: Write = %d, %v, want %d, nil", opts, n, err, len(faultyData))
+			}
+		} else {
+			err = os.WriteFile(name, []byte(faultyData), 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !opts.ShortReads {
+			buf := make([]byte, len(faultyData))
+			n, err := f.ReadAt(buf, 0)
+			if n != len(faultyData) || err != nil || string(buf) != faultyData {
+				t.Errorf("%+v: ReadAt = %d, %v, %q, want %d, nil, %q", opts, n, err, buf[:n], len(faultyData), faultyData)
+			}
+		}
+	}
+}
+
+func TestFaultyFSMaxChunk(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "chunk")
+	f, err := NewFaultyFS(osFS{}, FaultyOptions{ShortWrites: true}).Create(name)
+	if err != nil {
+		t.Fatalf("Create(%q): %s", name, err)
+	}
+	defer f.Close()
+	n, err := f.Write([]byte(faultyData))
+	if n != 1 || err != nil {
+		t.Errorf("Write with MaxChunk 0 = %d, %v, want 1, nil", n, err)
+	}
+}
+
+func TestFaultyFSErrors(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "missing")
+	fsys := NewFaultyFS(osFS{}, FaultyOptions{MaxChunk: 4, ShortReads: true, ShortWrites: true})
+	_, want := osFS{}.Open(name)
+	_, got := fsys.Open(name)
+	if diff := CompareErrors(want, got); diff != nil {
+		t.Errorf("Open(%q) error differs from osFS: %s", name, diff)
+	}
+	_, want = osFS{}.Stat(name)
+	_, got = fsys.Stat(name)
+	if diff := CompareErrors(want, got); diff != nil {
+		t.Errorf("Stat(%q) error differs from osFS: %s", name, diff)
+	}
+}
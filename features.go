@@ -0,0 +1,38 @@
+package fstesting
+
+// Features describes the optional behaviors of a FileSystem. Suite uses the
+// Features of the FileSystem under test to decide which tests to run and which
+// results to expect.
+type Features struct {
+	Symlinks        bool // Symlink, Readlink and Lstat are supported
+	Permissions     bool // Chmod is honored and reported by Stat
+	Timestamps      bool // Chtimes is honored and reported by Stat
+	CaseSensitive   bool // "a" and "A" name different files
+	AtomicRename    bool // Rename over an existing file is atomic
+	SparseFiles     bool // writing past EOF leaves an unallocated hole
+	LargeFiles      bool // files larger than 2GB are supported
+	Ownership       bool // Chown is honored and reported by FileInfo.Sys()
+	ExtendedAttrs   bool // the FileSystem implements XattrFileSystem
+	Snapshots       bool // the FileSystem implements SnapshotFileSystem
+	SpaceReporting  bool // the FileSystem implements StatfsFileSystem
+	ReadDirSnapshot bool // an open directory lists its entries as of the first read
+	MemoryMapped    bool // the FileSystem implements MmapFileSystem
+	Locking         bool // open Files implement LockingFile
+	AtimeOnRead     bool // reading updates the access time, as with strictatime or relatime
+	CreationTime    bool // FileInfo reports when a file was created
+	SortedReadDir   bool // Filer.ReadDir returns entries sorted by name, like fs.ReadDir
+	SpecialFiles    bool // the FileSystem implements SpecialFileSystem
+	HardLinks       bool // the FileSystem implements LinkFileSystem
+	Reflink         bool // the FileSystem implements CloneFileSystem
+	Durable         bool // synced data survives a crash and Suite.Reopen
+}
+
+// DefaultFeatures returns the feature set of a typical POSIX-like filesystem
+// without symlinks, ownership or any of the more exotic capabilities.
+func DefaultFeatures() Features {
+	return Features{
+		Permissions:   true,
+		Timestamps:    true,
+		CaseSensitive: true,
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package fstesting
+
+import "runtime"
+
+// OSFeatures returns the features of the native filesystem, as used through
+// the os package. Darwin filesystems are case insensitive by default, and
+// only the BSDs report creation times through Stat.
+func OSFeatures() Features {
+	return Features{
+		Symlinks:      true,
+		Permissions:   true,
+		Timestamps:    true,
+		CaseSensitive: runtime.GOOS != "darwin",
+		AtomicRename:  true,
+		SparseFiles:   true,
+		LargeFiles:    true,
+		Ownership:     true,
+		SortedReadDir: true,
+		HardLinks:     true,
+		CreationTime:  runtime.GOOS == "darwin" || runtime.GOOS == "freebsd" || runtime.GOOS == "netbsd",
+	}
+}
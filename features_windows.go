@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package fstesting
+
+// OSFeatures returns the features of the native filesystem, as used through
+// the os package. Windows only supports the read only permission bit and has
+// no notion of uid and gid ownership.
+func OSFeatures() Features {
+	return Features{
+		Timestamps:    true,
+		LargeFiles:    true,
+		CreationTime:  true,
+		SortedReadDir: true,
+		HardLinks:     true,
+	}
+}
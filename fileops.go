@@ -0,0 +1,462 @@
+package fstesting
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/absfs/absfs"
+)
+
+// testFileOperations covers reading, writing and seeking through open files.
+func (s *Suite) testFileOperations(t *testing.T, testDir string) {
+	dir := path.Join(testDir, "fileops")
+	s.mkdir(t, dir)
+
+	s.run(t, "Append", func(t *testing.T) {
+		name := path.Join(dir, "append")
+		s.writeFile(t, name, []byte("abc"))
+
+		f, err := s.FS.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatalf("OpenFile(%q, O_APPEND|O_WRONLY): %s", name, err)
+		}
+		_, err = f.Write([]byte("def"))
+		if err != nil {
+			f.Close()
+			t.Fatalf("Write: %s", err)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatalf("Close: %s", err)
+		}
+		s.checkContent(t, name, "abcdef")
+
+		// O_APPEND writes go to the end of the file regardless of the
+		// current offset.
+		f, err = s.FS.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatalf("OpenFile(%q, O_APPEND|O_WRONLY): %s", name, err)
+		}
+		_, err = f.Seek(0, io.SeekStart)
+		if err != nil {
+			f.Close()
+			t.Fatalf("Seek: %s", err)
+		}
+		_, err = f.Write([]byte("ghi"))
+		if err != nil {
+			f.Close()
+			t.Fatalf("Write after Seek: %s", err)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatalf("Close: %s", err)
+		}
+		s.checkContent(t, name, "abcdefghi")
+	})
+	s.run(t, "Trunc", func(t *testing.T) {
+		name := path.Join(dir, "trunc")
+		s.writeFile(t, name, []byte("hello world"))
+
+		f, err := s.FS.OpenFile(name, os.O_WRONLY|os.O_TRUNC, 0)
+		if err != nil {
+			t.Fatalf("OpenFile(%q, O_WRONLY|O_TRUNC): %s", name, err)
+		}
+		info, err := s.FS.Stat(name)
+		if err != nil {
+			f.Close()
+			t.Fatalf("Stat(%q): %s", name, err)
+		}
+		if info.Size() != 0 {
+			t.Errorf("Size() after O_TRUNC = %d, want 0", info.Size())
+		}
+		_, err = f.Write([]byte("hi"))
+		if err != nil {
+			f.Close()
+			t.Fatalf("Write: %s", err)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatalf("Close: %s", err)
+		}
+		s.checkContent(t, name, "hi")
+	})
+	s.run(t, "ReadAfterWrite", func(t *testing.T) {
+		name := path.Join(dir, "readafterwrite")
+		s.writeFile(t, name, []byte("stale stale stale"))
+
+		f, err := s.FS.OpenFile(name, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("OpenFile(%q, O_RDWR): %s", name, err)
+		}
+		defer f.Close()
+		_, err = f.Write([]byte("fresh"))
+		if err != nil {
+			t.Fatalf("Write: %s", err)
+		}
+		_, err = f.Seek(0, io.SeekStart)
+		if err != nil {
+			t.Fatalf("Seek: %s", err)
+		}
+
+		// The handle is not closed, so a FileSystem that only flushes
+		// writes on Close returns stale data here.
+		got, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if want := "fresh stale stale"; string(got) != want {
+			t.Errorf("Read after Write = %q, want %q", got, want)
+		}
+	})
+	s.run(t, "TruncateGrow", func(t *testing.T) {
+		name := path.Join(dir, "truncategrow")
+		s.writeFile(t, name, []byte("abc"))
+
+		err := s.FS.Truncate(name, 10)
+		if err != nil {
+			t.Fatalf("Truncate(%q, 10): %s", name, err)
+		}
+		info, err := s.FS.Stat(name)
+		if err != nil {
+			t.Fatalf("Stat(%q): %s", name, err)
+		}
+		if info.Size() != 10 {
+			t.Errorf("Size() after Truncate(%q, 10) = %d, want 10", name, info.Size())
+		}
+		s.checkContent(t, name, "abc"+string(make([]byte, 7)))
+
+		f, err := s.FS.OpenFile(name, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("OpenFile(%q, O_RDWR): %s", name, err)
+		}
+		err = f.Truncate(16)
+		f.Close()
+		if errors.Is(err, absfs.ErrNotImplemented) {
+			s.skip(t, "File.Truncate not implemented")
+		}
+		if err != nil {
+			t.Fatalf("File.Truncate(16): %s", err)
+		}
+		s.checkContent(t, name, "abc"+string(make([]byte, 13)))
+	})
+	s.run(t, "FileTruncate", func(t *testing.T) {
+		name := path.Join(dir, "filetruncate")
+		f, err := s.FS.Create(name)
+		if err != nil {
+			t.Fatalf("Create(%q): %s", name, err)
+		}
+		defer f.Close()
+		_, err = f.Write([]byte("hello world"))
+		if err != nil {
+			t.Fatalf("Write: %s", err)
+		}
+
+		err = f.Truncate(5)
+		if errors.Is(err, absfs.ErrNotImplemented) {
+			s.skip(t, "File.Truncate not implemented")
+		}
+		if err != nil {
+			t.Fatalf("File.Truncate(5): %s", err)
+		}
+		info, err := f.Stat()
+		if err != nil {
+			t.Fatalf("File.Stat: %s", err)
+		}
+		if info.Size() != 5 {
+			t.Errorf("File.Stat().Size() after File.Truncate(5) = %d, want 5", info.Size())
+		}
+		info, err = s.FS.Stat(name)
+		if err != nil {
+			t.Fatalf("Stat(%q): %s", name, err)
+		}
+		if info.Size() != 5 {
+			t.Errorf("Stat(%q).Size() after File.Truncate(5) = %d, want 5", name, info.Size())
+		}
+
+		// Truncate does not move the offset.
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			t.Fatalf("Seek: %s", err)
+		}
+		if offset != 11 {
+			t.Errorf("offset after File.Truncate(5) = %d, want 11", offset)
+		}
+	})
+	s.run(t, "Seek", func(t *testing.T) {
+		name := path.Join(dir, "seek")
+		payload := make([]byte, 100)
+		for i := range payload {
+			payload[i] = byte(i)
+		}
+		s.writeFile(t, name, payload)
+
+		f, err := s.FS.Open(name)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", name, err)
+		}
+		defer f.Close()
+
+		for _, tc := range []struct {
+			offset int64
+			whence int
+			want   int64
+			read   int
+		}{
+			{10, io.SeekStart, 10, 5},
+			{5, io.SeekCurrent, 20, 5},
+			{-5, io.SeekCurrent, 20, 0},
+			{-10, io.SeekEnd, 90, 10},
+			{-5, io.SeekCurrent, 95, 5},
+			{0, io.SeekStart, 0, 1},
+			{0, io.SeekCurrent, 1, 0},
+		} {
+			n, err := f.Seek(tc.offset, tc.whence)
+			if err != nil {
+				t.Fatalf("Seek(%d, %d): %s", tc.offset, tc.whence, err)
+			}
+			if n != tc.want {
+				t.Fatalf("Seek(%d, %d) = %d, want %d", tc.offset, tc.whence, n, tc.want)
+			}
+			buf := make([]byte, tc.read)
+			_, err = io.ReadFull(f, buf)
+			if err != nil {
+				t.Fatalf("Read at %d: %s", n, err)
+			}
+			if string(buf) != string(payload[n:n+int64(tc.read)]) {
+				t.Errorf("Read at %d = %v, want %v", n, buf, payload[n:n+int64(tc.read)])
+			}
+		}
+
+		n, err := f.Seek(200, io.SeekStart)
+		if err != nil {
+			t.Fatalf("Seek past EOF: %s", err)
+		}
+		if n != 200 {
+			t.Errorf("Seek(200, io.SeekStart) = %d, want 200", n)
+		}
+		read, err := f.Read(make([]byte, 10))
+		if read != 0 || err != io.EOF {
+			t.Errorf("Read past EOF = %d, %v, want 0, io.EOF", read, err)
+		}
+
+		_, err = f.Seek(-1, io.SeekStart)
+		if err == nil {
+			t.Error("Seek(-1, io.SeekStart) succeeded, want error")
+		}
+	})
+	s.run(t, "ReadAtWriteAt", func(t *testing.T) {
+		name := path.Join(dir, "at")
+		payload := make([]byte, 100)
+		for i := range payload {
+			payload[i] = byte(i)
+		}
+		s.writeFile(t, name, payload)
+
+		f, err := s.FS.OpenFile(name, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("OpenFile(%q, O_RDWR): %s", name, err)
+		}
+		defer f.Close()
+
+		_, err = f.ReadAt(make([]byte, 1), 0)
+		if errors.Is(err, absfs.ErrNotImplemented) {
+			s.skip(t, "ReadAt not implemented")
+		}
+
+		for _, tc := range []struct {
+			size int
+			off  int64
+			n    int
+			err  error
+		}{
+			{5, 0, 5, nil},
+			{20, 10, 20, nil},
+			{20, 20, 20, nil},
+			{10, 95, 5, io.EOF},
+			{5, 100, 0, io.EOF},
+			{5, 200, 0, io.EOF},
+		} {
+			buf := make([]byte, tc.size)
+			n, err := f.ReadAt(buf, tc.off)
+			if n != tc.n || err != tc.err {
+				t.Errorf("ReadAt(%d bytes, %d) = %d, %v, want %d, %v", tc.size, tc.off, n, err, tc.n, tc.err)
+				continue
+			}
+			if n > 0 && !bytes.Equal(buf[:n], payload[tc.off:tc.off+int64(n)]) {
+				t.Errorf("ReadAt(%d bytes, %d) = %v, want %v", tc.size, tc.off, buf[:n], payload[tc.off:tc.off+int64(n)])
+			}
+		}
+		_, err = f.ReadAt(make([]byte, 5), -1)
+		if err == nil {
+			t.Error("ReadAt at offset -1 succeeded, want error")
+		}
+
+		// ReadAt and WriteAt do not move the file offset.
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			t.Fatalf("Seek: %s", err)
+		}
+		if offset != 0 {
+			t.Errorf("offset after ReadAt = %d, want 0", offset)
+		}
+
+		n, err := f.WriteAt([]byte("XXXX"), 50)
+		if n != 4 || err != nil {
+			t.Fatalf("WriteAt(4 bytes, 50) = %d, %v, want 4, <nil>", n, err)
+		}
+		want := append([]byte{}, payload...)
+		copy(want[50:], "XXXX")
+		got := make([]byte, len(want))
+		n, err = f.ReadAt(got, 0)
+		if n != len(want) || err != nil {
+			t.Fatalf("ReadAt(%d bytes, 0) = %d, %v", len(got), n, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("content after WriteAt = %v, want %v", got, want)
+		}
+	})
+	s.run(t, "StatConsistency", func(t *testing.T) {
+		name := path.Join(dir, "stat")
+		subdir := path.Join(dir, "statdir")
+		s.writeFile(t, name, []byte("stat consistency"))
+		s.mkdir(t, subdir)
+
+		for _, p := range []string{name, subdir} {
+			want, err := s.FS.Stat(p)
+			if err != nil {
+				t.Fatalf("Stat(%q): %s", p, err)
+			}
+			f, err := s.FS.Open(p)
+			if err != nil {
+				t.Fatalf("Open(%q): %s", p, err)
+			}
+			got, err := f.Stat()
+			f.Close()
+			if err != nil {
+				t.Fatalf("File.Stat(%q): %s", p, err)
+			}
+			if err := compareInfo(got, want); err != nil {
+				t.Errorf("%q: File.Stat and Stat differ: %s", p, err)
+			}
+		}
+	})
+	s.run(t, "Sync", func(t *testing.T) {
+		name := path.Join(dir, "sync")
+		data := []byte("synced data")
+
+		f, err := s.FS.Create(name)
+		if err != nil {
+			t.Fatalf("Create(%q): %s", name, err)
+		}
+		_, err = f.Write(data)
+		if err != nil {
+			f.Close()
+			t.Fatalf("Write: %s", err)
+		}
+		err = f.Sync()
+		if errors.Is(err, syscall.ENOTSUP) || errors.Is(err, absfs.ErrNotImplemented) {
+			f.Close()
+			s.skip(t, fmt.Sprintf("Sync not supported: %s", err))
+		}
+		if err != nil {
+			f.Close()
+			t.Fatalf("Sync: %s", err)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatalf("Close: %s", err)
+		}
+		s.checkContent(t, name, string(data))
+
+		if s.Reopen == nil {
+			return
+		}
+		fs, err := s.Reopen()
+		if err != nil {
+			t.Fatalf("Reopen: %s", err)
+		}
+		got, err := fs.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile(%q) after Reopen: %s", name, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("content of %q after Reopen = %q, want %q", name, got, data)
+		}
+	})
+	s.run(t, "WriteString", func(t *testing.T) {
+		name := path.Join(dir, "writestring")
+		f, err := s.FS.Create(name)
+		if err != nil {
+			t.Fatalf("Create(%q): %s", name, err)
+		}
+		for _, str := range []string{"hello, ", "", "world"} {
+			n, err := f.WriteString(str)
+			if n != len(str) || err != nil {
+				t.Errorf("WriteString(%q) = %d, %v, want %d, <nil>", str, n, err, len(str))
+			}
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatalf("Close: %s", err)
+		}
+		s.checkContent(t, name, "hello, world")
+	})
+
+	s.run(t, "ReadFrom", func(t *testing.T) {
+		name := path.Join(dir, "readfrom")
+		data := bytes.Repeat([]byte("0123456789abcdef"), 100<<10/16)
+		f, err := s.FS.Create(name)
+		if err != nil {
+			t.Fatalf("Create(%q): %s", name, err)
+		}
+
+		// io.Copy uses the ReadFrom method of f when it has one.
+		n, err := io.Copy(f, bytes.NewReader(data))
+		if n != int64(len(data)) || err != nil {
+			t.Errorf("io.Copy = %d, %v, want %d, <nil>", n, err, len(data))
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatalf("Close: %s", err)
+		}
+		s.checkContent(t, name, string(data))
+	})
+	s.run(t, "Name", func(t *testing.T) {
+		name := path.Join(dir, "name")
+		s.writeFile(t, name, []byte("name"))
+
+		f, err := s.FS.Open(name)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", name, err)
+		}
+		if f.Name() != name {
+			t.Errorf("Name() = %q, want %q", f.Name(), name)
+		}
+		f.Close()
+
+		cwd, err := s.FS.Getwd()
+		if err != nil {
+			s.skip(t, fmt.Sprintf("Getwd: %s", err))
+		}
+		err = s.FS.Chdir(dir)
+		if err != nil {
+			s.skip(t, fmt.Sprintf("Chdir(%q): %s", dir, err))
+		}
+		defer s.FS.Chdir(cwd)
+
+		f, err = s.FS.Open("name")
+		if err != nil {
+			t.Fatalf("Open(%q) in %q: %s", "name", dir, err)
+		}
+		if f.Name() != "name" {
+			t.Errorf("Name() = %q, want %q", f.Name(), "name")
+		}
+		f.Close()
+	})
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package fstesting
+
+import "syscall"
+
+// oDirectory is the O_DIRECTORY open flag, or 0 where it is not defined.
+const oDirectory = syscall.O_DIRECTORY
+
+// oNofollow is the O_NOFOLLOW open flag, or 0 where it is not defined.
+const oNofollow = syscall.O_NOFOLLOW
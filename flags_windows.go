@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package fstesting
+
+// oDirectory is the O_DIRECTORY open flag, or 0 where it is not defined.
+const oDirectory = 0
+
+// oNofollow is the O_NOFOLLOW open flag, or 0 where it is not defined.
+const oNofollow = 0
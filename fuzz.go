@@ -0,0 +1,173 @@
+package fstesting
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absfs"
+)
+
+// FuzzChmod fuzzes Chmod with arbitrary modes, checking that the permission
+// bits reported by Stat match the permission bits requested. Call it from a
+// fuzz target:
+//
+//	func FuzzMyFSChmod(f *testing.F) {
+//		fstesting.FuzzChmod(f, myfs.New(), "/fuzz")
+//	}
+func FuzzChmod(f *testing.F, fs absfs.FileSystem, testDir string) {
+	for _, mode := range []uint32{0644, 0600, 0755, 0000, 0777} {
+		f.Add(mode)
+	}
+
+	name := path.Join(testDir, "fuzzchmod")
+	err := writeAll(fs, name, []byte("chmod"))
+	if err != nil {
+		f.Fatalf("writing %q: %s", name, err)
+	}
+	// A mode of 0000 must not prevent the file from being removed.
+	f.Cleanup(func() {
+		fs.Chmod(name, 0666)
+		fs.Remove(name)
+	})
+
+	f.Fuzz(func(t *testing.T, m uint32) {
+		mode := os.FileMode(m)
+		err := fs.Chmod(name, mode)
+		if err != nil {
+			t.Skipf("Chmod(%q, %s): %s", name, mode, err)
+		}
+		defer fs.Chmod(name, 0666)
+
+		info, err := fs.Stat(name)
+		if err != nil {
+			t.Fatalf("Stat(%q) after Chmod(%s): %s", name, mode, err)
+		}
+		if info.Mode().Perm() != mode.Perm() {
+			t.Errorf("Chmod(%s): Mode().Perm() = %s, want %s", mode, info.Mode().Perm(), mode.Perm())
+		}
+	})
+}
+
+// FuzzTruncate fuzzes Truncate with arbitrary sizes. Negative sizes must fail
+// without panicking. A successful Truncate must be reflected by Stat, must
+// keep the original content up to the new size and must zero fill any
+// extension. Absurdly large sizes may fail but must not panic.
+func FuzzTruncate(f *testing.F, fs absfs.FileSystem, testDir string) {
+	for _, size := range []int64{0, 5, 10, 100, 1 << 20, -1, 1 << 62} {
+		f.Add(size)
+	}
+
+	content := []byte("0123456789")
+	name := path.Join(testDir, "fuzztruncate")
+	f.Cleanup(func() {
+		fs.Remove(name)
+	})
+
+	f.Fuzz(func(t *testing.T, size int64) {
+		err := writeAll(fs, name, content)
+		if err != nil {
+			t.Fatalf("writing %q: %s", name, err)
+		}
+
+		err = fs.Truncate(name, size)
+		if size < 0 {
+			if err == nil {
+				t.Errorf("Truncate(%q, %d) succeeded, want error", name, size)
+			}
+			return
+		}
+		if err != nil {
+			if size <= 1<<30 {
+				t.Errorf("Truncate(%q, %d): %s", name, size, err)
+			}
+			return
+		}
+
+		info, err := fs.Stat(name)
+		if err != nil {
+			t.Fatalf("Stat(%q): %s", name, err)
+		}
+		if info.Size() != size {
+			t.Errorf("Truncate(%q, %d): Size() = %d", name, size, info.Size())
+		}
+
+		// Check the original content and up to 4KB of any extension.
+		n := size
+		if n > int64(len(content))+4096 {
+			n = int64(len(content)) + 4096
+		}
+		file, err := fs.Open(name)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", name, err)
+		}
+		defer file.Close()
+		buf := make([]byte, n)
+		_, err = io.ReadFull(file, buf)
+		if err != nil {
+			t.Fatalf("reading %d bytes of %q: %s", n, name, err)
+		}
+		for i, b := range buf {
+			want := byte(0)
+			if i < len(content) {
+				want = content[i]
+			}
+			if b != want {
+				t.Fatalf("Truncate(%q, %d): byte %d = %#x, want %#x", name, size, i, b, want)
+			}
+		}
+	})
+}
+
+// FuzzDeepPath fuzzes deeply nested paths. The fuzzed string is split into
+// slash separated components, the directories are created with MkdirAll and
+// a file is written and read back at the leaf. Filesystems may reject paths
+// that exceed their limits, but must do so with an error rather than a panic
+// or a stack overflow.
+func FuzzDeepPath(f *testing.F, fs absfs.FileSystem, testDir string) {
+	f.Add("a")
+	f.Add("a/b/c")
+	f.Add(strings.Repeat("d/", 100) + "leaf")
+	f.Add(strings.Repeat("x", 255) + "/" + strings.Repeat("y", 255))
+	f.Add(strings.Repeat("a/", 1000) + "leaf")
+	f.Add(strings.Repeat(strings.Repeat("z", 200)+"/", 25) + "leaf")
+
+	root := path.Join(testDir, "fuzzdeep")
+	f.Fuzz(func(t *testing.T, p string) {
+		var components []string
+		for _, c := range strings.Split(p, "/") {
+			if c == "" || c == "." || c == ".." || strings.ContainsRune(c, 0) {
+				continue
+			}
+			components = append(components, c)
+		}
+		if len(components) == 0 {
+			return
+		}
+		defer fs.RemoveAll(root)
+
+		dir := path.Join(append([]string{root}, components[:len(components)-1]...)...)
+		err := fs.MkdirAll(dir, 0777)
+		if err != nil {
+			t.Logf("MkdirAll with %d components: %s", len(components)-1, err)
+			return
+		}
+
+		name := path.Join(dir, components[len(components)-1])
+		data := []byte(p)
+		err = writeAll(fs, name, data)
+		if err != nil {
+			t.Logf("writing leaf at depth %d: %s", len(components), err)
+			return
+		}
+		got, err := fs.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile at depth %d after successful write: %s", len(components), err)
+		}
+		if string(got) != string(data) {
+			t.Errorf("ReadFile at depth %d = %q, want %q", len(components), got, data)
+		}
+	})
+}
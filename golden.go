@@ -0,0 +1,148 @@
+package fstesting
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absfs"
+)
+
+// Observation is a single result recorded in a golden file.
+type Observation struct {
+	Err  string `json:"err,omitempty"`
+	Mode string `json:"mode,omitempty"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// golden collects the observations made by one test and compares them
+// against, or writes them to, the test's golden file when the test ends.
+type golden struct {
+	file   string
+	dir    string
+	update bool
+	obs    map[string]Observation
+}
+
+// golden returns a recorder for t whose error strings have dir replaced by a
+// fixed placeholder, so that golden files don't depend on the test directory.
+func (s *Suite) golden(t *testing.T, dir string) *golden {
+	g := &golden{
+		file:   filepath.Join(s.GoldenDir, strings.ReplaceAll(t.Name(), "/", "_")+".json"),
+		dir:    dir,
+		update: s.UpdateGolden,
+		obs:    make(map[string]Observation),
+	}
+	t.Cleanup(func() {
+		err := g.check()
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	return g
+}
+
+// observe records err and the state of name in fs after an operation.
+func (g *golden) observe(key string, fs absfs.FileSystem, name string, err error) {
+	var o Observation
+	if err != nil {
+		o.Err = strings.ReplaceAll(err.Error(), g.dir, "$DIR")
+	}
+	info, err := fs.Stat(name)
+	if err != nil {
+		o.Mode = "absent"
+	} else {
+		o.Mode = info.Mode().String()
+		if !info.IsDir() {
+			o.Size = info.Size()
+		}
+	}
+	g.obs[key] = o
+}
+
+// check writes the golden file if g.update is set and otherwise
+// returns an error listing every observation that differs from it.
+func (g *golden) check() error {
+	if g.update {
+		data, err := json.MarshalIndent(g.obs, "", "  ")
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(filepath.Dir(g.file), 0777)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(g.file, append(data, '\n'), 0666)
+	}
+
+	data, err := os.ReadFile(g.file)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no golden file %s, set Suite.UpdateGolden to create it", g.file)
+	}
+	if err != nil {
+		return err
+	}
+	var want map[string]Observation
+	err = json.Unmarshal(data, &want)
+	if err != nil {
+		return fmt.Errorf("golden file %s: %s", g.file, err)
+	}
+
+	keys := make(map[string]bool)
+	for k := range want {
+		keys[k] = true
+	}
+	for k := range g.obs {
+		keys[k] = true
+	}
+	var list []string
+	for k := range keys {
+		got, ok1 := g.obs[k]
+		exp, ok2 := want[k]
+		switch {
+		case !ok1:
+			list = append(list, fmt.Sprintf("%s: not observed", k))
+		case !ok2:
+			list = append(list, fmt.Sprintf("%s: not in golden file", k))
+		case got != exp:
+			list = append(list, fmt.Sprintf("%s: got %+v, want %+v", k, got, exp))
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	sort.Strings(list)
+	return fmt.Errorf("results differ from golden file %s:\n\t%s", g.file, strings.Join(list, "\n\t"))
+}
+
+// testGolden runs each of the Operations under a set of preconditions and
+// compares the results against the golden files in GoldenDir.
+func (s *Suite) testGolden(t *testing.T, testDir string) {
+	if s.GoldenDir == "" {
+		s.skip(t, "GoldenDir not set")
+	}
+	dir := path.Join(testDir, "golden")
+	s.mkdir(t, dir)
+	g := s.golden(t, dir)
+
+	testNo := 0
+	for _, op := range Operations {
+		for _, condition := range []string{"notcreated", "created", "dir"} {
+			testNo++
+			name, err := pretest(s.FS, dir, &Testcase{TestNo: testNo, PreCondition: condition})
+			if err != nil {
+				t.Fatalf("%s %s: %s", op.Op, condition, err)
+			}
+			errs := make(map[string]*ErrorReport)
+			op.Run(s.FS, name, errs)
+			for _, report := range errs {
+				g.observe(op.Op+"/"+condition, s.FS, name, report.Err)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package fstesting
+
+import (
+	"math/bits"
+	"time"
+)
+
+// histogramSub is the number of linear sub-buckets each power of two is
+// split into, which bounds the error of a quantile to 1/histogramSub.
+const histogramSub = 8
+
+// histogram records durations in buckets that grow exponentially, so that it
+// covers nanoseconds to hours in constant space with a bounded relative
+// error. Methods on a nil histogram do nothing, so callers need not check
+// whether latencies are being recorded.
+type histogram struct {
+	buckets [64 * histogramSub]uint64
+	count   uint64
+	max     time.Duration
+}
+
+// start returns the time an operation starts, or the zero time if h is nil.
+func (h *histogram) start() time.Time {
+	if h == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// record adds the time elapsed since start to h.
+func (h *histogram) record(start time.Time) {
+	if h == nil {
+		return
+	}
+	d := time.Since(start)
+	if d < 0 {
+		d = 0
+	}
+	h.buckets[histogramBucket(uint64(d))]++
+	h.count++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// quantile returns the upper bound of the bucket holding the q quantile of the
+// recorded durations, clamped to the largest duration recorded.
+func (h *histogram) quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(h.count-1)) + 1
+	var seen uint64
+	for i, n := range h.buckets {
+		seen += n
+		if seen >= rank {
+			d := time.Duration(histogramLower(i+1) - 1)
+			if d > h.max {
+				d = h.max
+			}
+			return d
+		}
+	}
+	return h.max
+}
+
+// histogramBucket returns the bucket of v. Values below histogramSub have a
+// bucket each, larger values share a bucket with the values that agree with
+// them in their top bits.
+func histogramBucket(v uint64) int {
+	if v < histogramSub {
+		return int(v)
+	}
+	shift := bits.Len64(v) - bits.Len64(histogramSub)
+	return (shift+1)*histogramSub + int(v>>shift) - histogramSub
+}
+
+// histogramLower returns the smallest value in bucket i.
+func histogramLower(i int) uint64 {
+	if i < histogramSub {
+		return uint64(i)
+	}
+	shift := i/histogramSub - 1
+	return uint64(histogramSub+i%histogramSub) << shift
+}
@@ -0,0 +1,93 @@
+package fstesting
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	last := 0
+	for v := uint64(0); v < 1<<20; v++ {
+		i := histogramBucket(v)
+		lo, hi := histogramLower(i), histogramLower(i+1)
+		if v < lo || v >= hi {
+			t.Fatalf("histogramBucket(%d) = %d, which holds [%d, %d)", v, i, lo, hi)
+		}
+		if i < last {
+			t.Fatalf("histogramBucket(%d) = %d, below the bucket %d of a smaller value", v, i, last)
+		}
+		last = i
+		// Buckets are exact below histogramSub and at most 1/histogramSub
+		// of their lower bound wide above it.
+		if width := hi - lo; width > 1 && width*histogramSub > lo {
+			t.Fatalf("bucket %d holds [%d, %d), wider than 1/%d of its lower bound", i, lo, hi, histogramSub)
+		}
+	}
+}
+
+// add records d in h as if an operation had taken that long.
+func (h *histogram) add(d time.Duration) {
+	h.buckets[histogramBucket(uint64(d))]++
+	h.count++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	h := new(histogram)
+	if got := h.quantile(0.5); got != 0 {
+		t.Errorf("quantile(0.5) of an empty histogram = %s, want 0", got)
+	}
+	for i := 1; i <= 1000; i++ {
+		h.add(time.Duration(i) * time.Microsecond)
+	}
+	for _, tt := range []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, time.Microsecond},
+		{0.5, 500 * time.Microsecond},
+		{0.95, 950 * time.Microsecond},
+		{0.99, 990 * time.Microsecond},
+		{1, 1000 * time.Microsecond},
+	} {
+		got := h.quantile(tt.q)
+		if got < tt.want || got > tt.want+tt.want/histogramSub {
+			t.Errorf("quantile(%v) = %s, want between %s and %s", tt.q, got, tt.want, tt.want+tt.want/histogramSub)
+		}
+	}
+	if got := h.quantile(1); got != h.max {
+		t.Errorf("quantile(1) = %s, want the maximum %s", got, h.max)
+	}
+
+	// A nil histogram records nothing.
+	var nilh *histogram
+	nilh.record(nilh.start())
+}
+
+func TestWriteLatencyTable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping benchmark in short mode")
+	}
+	bm := &Benchmark{FS: osFS{}, TestDir: t.TempDir(), Latency: true}
+	testing.Benchmark(bm.BenchmarkStat)
+
+	buf := new(bytes.Buffer)
+	err := bm.WriteLatencyTable(buf)
+	if err != nil {
+		t.Fatalf("WriteLatencyTable: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("WriteLatencyTable wrote %d lines, want a header and one row:\n%s", len(lines), buf)
+	}
+	if got := strings.Fields(lines[0]); strings.Join(got, " ") != "operation count p50 p95 p99 max" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); len(fields) != 6 || fields[0] != "Stat" || fields[1] == "0" {
+		t.Errorf("row = %q, want Stat with a non-zero count", lines[1])
+	}
+}
@@ -0,0 +1,388 @@
+package fstesting
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// InjectFS wraps a FileSystem and fails selected operations with chosen
+// errors. Rules are added with Fail:
+//
+//	ifs := fstesting.NewInjectFS(base)
+//	ifs.Fail("Write", "*.bin", syscall.ENOSPC)
+//
+// Injected errors are returned as *os.PathError, or *os.LinkError for
+// Rename, just as the os package would return them. Open and Create are
+// matched by the "OpenFile" operation.
+//
+// DropUnsynced and Crash simulate losing power: writes held since the last
+// Sync are discarded and the open files fail from then on.
+type InjectFS struct {
+	absfs.FileSystem
+
+	mu      sync.Mutex
+	rules   []injectRule
+	crashed bool
+}
+
+type injectRule struct {
+	op      string
+	pattern string
+	err     error
+	after   bool
+}
+
+// CrashPoint is the point at which CrashRename makes a Rename fail.
+type CrashPoint int
+
+const (
+	// CrashBefore fails the Rename without calling the wrapped FileSystem.
+	CrashBefore CrashPoint = iota
+	// CrashAfter calls the wrapped Rename and then fails, as if the caller
+	// crashed after the rename reached storage but before it returned.
+	CrashAfter
+)
+
+// NewInjectFS returns an InjectFS wrapping base with no failures configured.
+func NewInjectFS(base absfs.FileSystem) *InjectFS {
+	return &InjectFS{FileSystem: base}
+}
+
+// Fail makes every future call of the method named op fail with err when its
+// path matches pattern. Patterns use path.Match syntax; a pattern without a
+// slash is matched against the base name of the path. File methods such as
+// Read, Write and Sync are matched against the name the file was opened with.
+func (ifs *InjectFS) Fail(op, pattern string, err error) {
+	ifs.mu.Lock()
+	defer ifs.mu.Unlock()
+	ifs.rules = append(ifs.rules, injectRule{op, pattern, err, false})
+}
+
+// CrashRename makes every future Rename whose old path matches pattern fail
+// with err at point. Patterns are matched as in Fail.
+func (ifs *InjectFS) CrashRename(pattern string, point CrashPoint, err error) {
+	ifs.mu.Lock()
+	defer ifs.mu.Unlock()
+	ifs.rules = append(ifs.rules, injectRule{"Rename", pattern, err, point == CrashAfter})
+}
+
+// errUnsynced marks DropUnsynced rules. It is never returned to callers.
+var errUnsynced = errors.New("fstesting: unsynced")
+
+// DropUnsynced makes files opened from now on whose path matches pattern
+// hold their writes in memory until Sync or Close passes them on to the
+// wrapped file, so that Crash can discard them. Reads and Seek do not see
+// held writes. Patterns are matched as in Fail.
+func (ifs *InjectFS) DropUnsynced(pattern string) {
+	ifs.mu.Lock()
+	defer ifs.mu.Unlock()
+	ifs.rules = append(ifs.rules, injectRule{"DropUnsynced", pattern, errUnsynced, false})
+}
+
+// Crash discards the writes held by files opened under DropUnsynced. From
+// then on every method of a file opened before the Crash fails with EIO,
+// and Close releases the wrapped file without passing on held writes.
+func (ifs *InjectFS) Crash() {
+	ifs.mu.Lock()
+	defer ifs.mu.Unlock()
+	ifs.crashed = true
+}
+
+// Reset removes all configured failures and clears a Crash for files opened
+// afterwards.
+func (ifs *InjectFS) Reset() {
+	ifs.mu.Lock()
+	defer ifs.mu.Unlock()
+	ifs.rules = nil
+	ifs.crashed = false
+}
+
+// inject returns the error configured for op on name, or nil.
+func (ifs *InjectFS) inject(op, name string) error {
+	return ifs.match(op, name, false)
+}
+
+// match returns the error of the first rule for op on name that fails before
+// or, if after is set, after calling the wrapped FileSystem.
+func (ifs *InjectFS) match(op, name string, after bool) error {
+	ifs.mu.Lock()
+	defer ifs.mu.Unlock()
+	for _, r := range ifs.rules {
+		if r.op != op || r.after != after {
+			continue
+		}
+		target := name
+		if !strings.Contains(r.pattern, "/") {
+			target = path.Base(name)
+		}
+		if ok, _ := path.Match(r.pattern, target); ok {
+			return r.err
+		}
+	}
+	return nil
+}
+
+// pathError returns the injected error for op on name wrapped in an
+// *os.PathError, or nil.
+func (ifs *InjectFS) pathError(op, name string) error {
+	err := ifs.inject(op, name)
+	if err == nil {
+		return nil
+	}
+	osop := strings.ToLower(op)
+	if op == "OpenFile" {
+		osop = "open"
+	}
+	return &os.PathError{Op: osop, Path: name, Err: err}
+}
+
+func (ifs *InjectFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	if err := ifs.pathError("OpenFile", name); err != nil {
+		return nil, err
+	}
+	f, err := ifs.FileSystem.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	drop := ifs.inject("DropUnsynced", name) != nil
+	ifs.mu.Lock()
+	crashed := ifs.crashed
+	ifs.mu.Unlock()
+	return &injectFile{File: f, ifs: ifs, name: name, drop: drop, crashed: crashed}, nil
+}
+
+func (ifs *InjectFS) Open(name string) (absfs.File, error) {
+	return ifs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (ifs *InjectFS) Create(name string) (absfs.File, error) {
+	return ifs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (ifs *InjectFS) Mkdir(name string, perm os.FileMode) error {
+	if err := ifs.pathError("Mkdir", name); err != nil {
+		return err
+	}
+	return ifs.FileSystem.Mkdir(name, perm)
+}
+
+func (ifs *InjectFS) MkdirAll(name string, perm os.FileMode) error {
+	if err := ifs.pathError("MkdirAll", name); err != nil {
+		return err
+	}
+	return ifs.FileSystem.MkdirAll(name, perm)
+}
+
+func (ifs *InjectFS) Remove(name string) error {
+	if err := ifs.pathError("Remove", name); err != nil {
+		return err
+	}
+	return ifs.FileSystem.Remove(name)
+}
+
+func (ifs *InjectFS) RemoveAll(name string) error {
+	if err := ifs.pathError("RemoveAll", name); err != nil {
+		return err
+	}
+	return ifs.FileSystem.RemoveAll(name)
+}
+
+func (ifs *InjectFS) Rename(oldpath, newpath string) error {
+	if err := ifs.inject("Rename", oldpath); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	err := ifs.FileSystem.Rename(oldpath, newpath)
+	if err != nil {
+		return err
+	}
+	if err := ifs.match("Rename", oldpath, true); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	return nil
+}
+
+func (ifs *InjectFS) Stat(name string) (os.FileInfo, error) {
+	if err := ifs.pathError("Stat", name); err != nil {
+		return nil, err
+	}
+	return ifs.FileSystem.Stat(name)
+}
+
+func (ifs *InjectFS) Chmod(name string, mode os.FileMode) error {
+	if err := ifs.pathError("Chmod", name); err != nil {
+		return err
+	}
+	return ifs.FileSystem.Chmod(name, mode)
+}
+
+func (ifs *InjectFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if err := ifs.pathError("Chtimes", name); err != nil {
+		return err
+	}
+	return ifs.FileSystem.Chtimes(name, atime, mtime)
+}
+
+func (ifs *InjectFS) Chown(name string, uid, gid int) error {
+	if err := ifs.pathError("Chown", name); err != nil {
+		return err
+	}
+	return ifs.FileSystem.Chown(name, uid, gid)
+}
+
+func (ifs *InjectFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := ifs.pathError("ReadDir", name); err != nil {
+		return nil, err
+	}
+	return ifs.FileSystem.ReadDir(name)
+}
+
+func (ifs *InjectFS) ReadFile(name string) ([]byte, error) {
+	if err := ifs.pathError("ReadFile", name); err != nil {
+		return nil, err
+	}
+	return ifs.FileSystem.ReadFile(name)
+}
+
+func (ifs *InjectFS) Truncate(name string, size int64) error {
+	if err := ifs.pathError("Truncate", name); err != nil {
+		return err
+	}
+	return ifs.FileSystem.Truncate(name, size)
+}
+
+type injectFile struct {
+	absfs.File
+	ifs  *InjectFS
+	name string
+
+	mu      sync.Mutex
+	drop    bool           // hold writes until Sync or Close
+	held    []pendingWrite // writes since the last Sync
+	crashed bool           // the InjectFS had crashed when the file was opened
+}
+
+// pendingWrite is a write held by a file opened under DropUnsynced.
+type pendingWrite struct {
+	data []byte
+	off  int64
+	at   bool // written with WriteAt at off
+}
+
+// fail returns the error for op on the file: EIO after a Crash, or the
+// injected error, or nil.
+func (f *injectFile) fail(op string) error {
+	f.ifs.mu.Lock()
+	crashed := f.ifs.crashed && !f.crashed
+	f.ifs.mu.Unlock()
+	if crashed {
+		return &os.PathError{Op: strings.ToLower(op), Path: f.name, Err: syscall.EIO}
+	}
+	return f.ifs.pathError(op, f.name)
+}
+
+// hold keeps a copy of p to be written at off, or at the file offset if at
+// is false, when the file is flushed.
+func (f *injectFile) hold(p []byte, off int64, at bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.held = append(f.held, pendingWrite{append([]byte(nil), p...), off, at})
+}
+
+// flush passes the held writes on to the wrapped file in order.
+func (f *injectFile) flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.held) > 0 {
+		w := f.held[0]
+		var err error
+		if w.at {
+			_, err = f.File.WriteAt(w.data, w.off)
+		} else {
+			_, err = f.File.Write(w.data)
+		}
+		if err != nil {
+			return err
+		}
+		f.held = f.held[1:]
+	}
+	return nil
+}
+
+func (f *injectFile) Read(p []byte) (int, error) {
+	if err := f.fail("Read"); err != nil {
+		return 0, err
+	}
+	return f.File.Read(p)
+}
+
+func (f *injectFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.fail("ReadAt"); err != nil {
+		return 0, err
+	}
+	return f.File.ReadAt(p, off)
+}
+
+func (f *injectFile) Write(p []byte) (int, error) {
+	if err := f.fail("Write"); err != nil {
+		return 0, err
+	}
+	if f.drop {
+		f.hold(p, 0, false)
+		return len(p), nil
+	}
+	return f.File.Write(p)
+}
+
+func (f *injectFile) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.fail("WriteAt"); err != nil {
+		return 0, err
+	}
+	if f.drop {
+		f.hold(p, off, true)
+		return len(p), nil
+	}
+	return f.File.WriteAt(p, off)
+}
+
+func (f *injectFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *injectFile) Truncate(size int64) error {
+	if err := f.fail("Truncate"); err != nil {
+		return err
+	}
+	return f.File.Truncate(size)
+}
+
+func (f *injectFile) Sync() error {
+	if err := f.fail("Sync"); err != nil {
+		return err
+	}
+	err := f.flush()
+	if err != nil {
+		return err
+	}
+	return f.File.Sync()
+}
+
+func (f *injectFile) Close() error {
+	if err := f.fail("Close"); err != nil {
+		f.File.Close()
+		return err
+	}
+	err := f.flush()
+	if err != nil {
+		f.File.Close()
+		return err
+	}
+	return f.File.Close()
+}
@@ -0,0 +1,204 @@
+package fstesting
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestInjectFSWrite(t *testing.T) {
+	ifs := NewInjectFS(osFS{})
+	name := filepath.Join(t.TempDir(), "full.bin")
+	ifs.Fail("Write", "*.bin", syscall.ENOSPC)
+
+	f, err := ifs.Create(name)
+	if err != nil {
+		t.Fatalf("Create(%q): %s", name, err)
+	}
+	defer f.Close()
+	n, err := f.Write([]byte("data"))
+	if n != 0 {
+		t.Errorf("Write returned n = %d, want 0", n)
+	}
+	var perr *os.PathError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Write error = %#v, want *os.PathError", err)
+	}
+	if perr.Op != "write" || perr.Path != name {
+		t.Errorf("PathError = {%q, %q}, want {%q, %q}", perr.Op, perr.Path, "write", name)
+	}
+	if !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("Write error = %s, want ENOSPC", err)
+	}
+
+	// Other operations on the same file are unaffected.
+	_, err = f.WriteAt([]byte("data"), 0)
+	if err != nil {
+		t.Errorf("WriteAt: %s", err)
+	}
+}
+
+func TestInjectFSRename(t *testing.T) {
+	ifs := NewInjectFS(osFS{})
+	dir := t.TempDir()
+	oldpath, newpath := filepath.Join(dir, "old"), filepath.Join(dir, "new")
+	err := writeAll(osFS{}, oldpath, []byte("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ifs.Fail("Rename", "old", syscall.EXDEV)
+
+	err = ifs.Rename(oldpath, newpath)
+	var lerr *os.LinkError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("Rename error = %#v, want *os.LinkError", err)
+	}
+	if lerr.Op != "rename" || lerr.Old != oldpath || lerr.New != newpath || !errors.Is(err, syscall.EXDEV) {
+		t.Errorf("Rename error = %s, want rename %s %s: EXDEV", err, oldpath, newpath)
+	}
+	if _, err := os.Stat(oldpath); err != nil {
+		t.Errorf("failed Rename moved the file: %s", err)
+	}
+
+	ifs.Reset()
+	err = ifs.Rename(oldpath, newpath)
+	if err != nil {
+		t.Fatalf("Rename after Reset: %s", err)
+	}
+}
+
+func TestInjectFSCrashRename(t *testing.T) {
+	for _, tt := range []struct {
+		point   CrashPoint
+		renamed bool
+	}{
+		{CrashBefore, false},
+		{CrashAfter, true},
+	} {
+		ifs := NewInjectFS(osFS{})
+		dir := t.TempDir()
+		oldpath, newpath := filepath.Join(dir, "old"), filepath.Join(dir, "new")
+		err := writeAll(osFS{}, oldpath, []byte("old"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ifs.CrashRename("old", tt.point, syscall.EIO)
+
+		err = ifs.Rename(oldpath, newpath)
+		var lerr *os.LinkError
+		if !errors.As(err, &lerr) || !errors.Is(err, syscall.EIO) {
+			t.Errorf("point %d: Rename error = %#v, want *os.LinkError wrapping EIO", tt.point, err)
+		}
+		_, err = os.Stat(newpath)
+		if renamed := err == nil; renamed != tt.renamed {
+			t.Errorf("point %d: renamed = %t, want %t", tt.point, renamed, tt.renamed)
+		}
+	}
+}
+
+func TestInjectFSMatching(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	err := os.Mkdir(sub, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{filepath.Join(dir, "a.txt"), filepath.Join(sub, "a.txt"), filepath.Join(sub, "b.txt")} {
+		err := writeAll(osFS{}, name, []byte("x"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		pattern string
+		name    string
+		fail    bool
+	}{
+		// Patterns without a slash match the base name in any directory.
+		{"a.txt", "a.txt", true},
+		{"a.txt", "sub/a.txt", true},
+		{"*.txt", "sub/b.txt", true},
+		{"a.*", "sub/b.txt", false},
+		// Patterns with a slash match the full path.
+		{filepath.ToSlash(sub) + "/a.txt", "sub/a.txt", true},
+		{filepath.ToSlash(sub) + "/a.txt", "a.txt", false},
+		{filepath.ToSlash(sub) + "/*", "sub/b.txt", true},
+		{"sub/a.txt", "sub/a.txt", false},
+	} {
+		ifs := NewInjectFS(osFS{})
+		ifs.Fail("Stat", tt.pattern, syscall.EACCES)
+		name := filepath.Join(dir, filepath.FromSlash(tt.name))
+		_, err := ifs.Stat(name)
+		if failed := errors.Is(err, syscall.EACCES); failed != tt.fail {
+			t.Errorf("pattern %q on %q: failed = %t, want %t (%v)", tt.pattern, tt.name, failed, tt.fail, err)
+		}
+	}
+}
+
+func TestInjectFSDropUnsynced(t *testing.T) {
+	ifs := NewInjectFS(osFS{})
+	dir := t.TempDir()
+	name := filepath.Join(dir, "held")
+	ifs.DropUnsynced("held")
+
+	f, err := ifs.Create(name)
+	if err != nil {
+		t.Fatalf("Create(%q): %s", name, err)
+	}
+	defer f.Close()
+	for _, s := range []string{"synced", "held"} {
+		_, err = f.Write([]byte(s))
+		if err != nil {
+			t.Fatalf("Write(%q): %s", s, err)
+		}
+		if s == "synced" {
+			err = f.Sync()
+			if err != nil {
+				t.Fatalf("Sync: %s", err)
+			}
+		}
+	}
+	data, _ := os.ReadFile(name)
+	if string(data) != "synced" {
+		t.Errorf("file holds %q before Crash, want %q", data, "synced")
+	}
+
+	ifs.Crash()
+	_, err = f.Write([]byte("more"))
+	if !errors.Is(err, syscall.EIO) {
+		t.Errorf("Write after Crash = %v, want EIO", err)
+	}
+	err = f.Close()
+	if !errors.Is(err, syscall.EIO) {
+		t.Errorf("Close after Crash = %v, want EIO", err)
+	}
+	data, _ = os.ReadFile(name)
+	if string(data) != "synced" {
+		t.Errorf("file holds %q after Crash, want %q", data, "synced")
+	}
+
+	// Close passes held writes on, and other files are not held.
+	ifs.Reset()
+	ifs.DropUnsynced("held")
+	for _, name := range []string{name, filepath.Join(dir, "other")} {
+		f, err := ifs.Create(name)
+		if err != nil {
+			t.Fatalf("Create(%q): %s", name, err)
+		}
+		_, err = f.WriteAt([]byte("data"), 2)
+		if err != nil {
+			t.Fatalf("WriteAt: %s", err)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatalf("Close: %s", err)
+		}
+		data, _ := os.ReadFile(name)
+		if string(data) != "\x00\x00data" {
+			t.Errorf("%q holds %q after Close, want %q", name, data, "\x00\x00data")
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package fstesting
+
+import "os"
+
+// XattrFileSystem is implemented by FileSystems that support extended
+// attributes. Suite tests it when Features.ExtendedAttrs is set.
+//
+// Attribute names include their namespace, e.g. "user.comment". Getxattr and
+// Removexattr return an error if the attribute is not set, and Listxattr
+// returns the names of all attributes set on name.
+type XattrFileSystem interface {
+	Getxattr(name, attr string) ([]byte, error)
+	Setxattr(name, attr string, value []byte) error
+	Listxattr(name string) ([]string, error)
+	Removexattr(name, attr string) error
+}
+
+// SnapshotFileSystem is implemented by FileSystems, typically copy-on-write
+// ones, that can capture their state and later return to it. Suite tests it
+// when Features.Snapshots is set.
+//
+// Snapshot records the current contents of the whole FileSystem and returns
+// an identifier for the snapshot. Restore returns the FileSystem to the state
+// recorded by the snapshot id, discarding all changes made since.
+type SnapshotFileSystem interface {
+	Snapshot() (id string, err error)
+	Restore(id string) error
+}
+
+// CloneFileSystem is implemented by FileSystems that can clone a file
+// without copying its data, like reflinks on btrfs or clonefile on APFS.
+// Suite tests it when Features.Reflink is set.
+//
+// Clone creates dst with the contents of src. The two files share storage
+// until either is modified, after which they are independent.
+type CloneFileSystem interface {
+	Clone(src, dst string) error
+}
+
+// SpaceInfo reports the storage space of a FileSystem in bytes. Free counts
+// all unused space and Available the part of it usable by the caller, which
+// may be less if space is reserved or subject to quota.
+type SpaceInfo struct {
+	Total     uint64
+	Free      uint64
+	Available uint64
+}
+
+// StatfsFileSystem is implemented by FileSystems that report their storage
+// space. Suite tests it when Features.SpaceReporting is set.
+//
+// Statfs returns the space of the storage holding name.
+type StatfsFileSystem interface {
+	Statfs(name string) (SpaceInfo, error)
+}
+
+// MmapFileSystem is implemented by FileSystems that can map files into memory
+// for zero-copy access. Suite tests it when Features.MemoryMapped is set.
+//
+// Map maps the whole of name, returning its contents and a function that
+// unmaps them. flag is os.O_RDONLY or os.O_RDWR. Changes made through a
+// mapping opened with os.O_RDWR are written to the file no later than the
+// call to unmap. The slice must not be used after unmap is called.
+// FileSystems that only support read-only mappings return an error for
+// os.O_RDWR.
+type MmapFileSystem interface {
+	Map(name string, flag int) (data []byte, unmap func() error, err error)
+}
+
+// LinkFileSystem is implemented by FileSystems that support hard links.
+// Suite tests it when Features.HardLinks is set.
+//
+// Link creates newname as another name for the file oldname, like os.Link.
+type LinkFileSystem interface {
+	Link(oldname, newname string) error
+}
+
+// SpecialFileSystem is implemented by FileSystems that can create the POSIX
+// special file types. Suite tests it when Features.SpecialFiles is set, and
+// otherwise expects both methods to fail with ENOTSUP.
+//
+// Mkfifo creates a named pipe with permissions perm. Mknod creates a device
+// node; mode holds os.ModeDevice, os.ModeCharDevice for character devices,
+// and the permissions, and dev is the device number.
+type SpecialFileSystem interface {
+	Mkfifo(name string, perm os.FileMode) error
+	Mknod(name string, mode os.FileMode, dev uint64) error
+}
+
+// LockingFile is implemented by open Files that support exclusive advisory
+// locks, like flock(2). Suite tests it when Features.Locking is set.
+//
+// Locks belong to the open File, so two Files opened on the same name
+// exclude each other even within one process. Lock blocks until the lock is
+// acquired. TryLock acquires the lock and returns true if it is free and
+// returns false without waiting if it is not. Unlock releases the lock, as
+// does closing the File.
+type LockingFile interface {
+	Lock() error
+	TryLock() (bool, error)
+	Unlock() error
+}
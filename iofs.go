@@ -0,0 +1,221 @@
+package fstesting
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/absfs/absfs"
+)
+
+// IOFS returns the subtree of fsys rooted at dir as an io/fs.FS. It uses
+// fsys.Sub, falling back to absfs.FilerToFS if Sub is not implemented.
+func IOFS(fsys absfs.FileSystem, dir string) (fs.FS, error) {
+	fsys2, err := fsys.Sub(dir)
+	if errors.Is(err, absfs.ErrNotImplemented) {
+		return absfs.FilerToFS(fsys, dir)
+	}
+	return fsys2, err
+}
+
+// RunFSTest runs the standard library's fstest.TestFS conformance checks
+// against fsys, which must contain at least the expected files.
+func RunFSTest(t *testing.T, fsys fs.FS, expected ...string) {
+	t.Helper()
+	err := fstest.TestFS(fsys, expected...)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// testNewFilerMethods covers the io/fs interoperability methods of Filer:
+// ReadDir, ReadFile and Sub.
+func (s *Suite) testNewFilerMethods(t *testing.T, testDir string) {
+	dir := path.Join(testDir, "filer")
+	s.mkdir(t, path.Join(dir, "b", "d"))
+	files := []string{"a.txt", "b/c.txt", "b/d/e.txt"}
+	s.populate(t, dir, files, nil)
+
+	fsys, err := IOFS(s.FS, dir)
+	if err != nil {
+		t.Fatalf("IOFS(%q): %s", dir, err)
+	}
+
+	s.run(t, "TestFS", func(t *testing.T) {
+		RunFSTest(t, fsys, files...)
+	})
+	s.run(t, "WalkDir", func(t *testing.T) {
+		// fs.WalkDir visits entries depth first in lexical order within
+		// each directory.
+		want := []string{".", "a.txt", "b", "b/c.txt", "b/d", "b/d/e.txt"}
+
+		var got []string
+		err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			got = append(got, name)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WalkDir: %s", err)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("WalkDir visited %q, want %q", got, want)
+		}
+	})
+	s.run(t, "Glob", func(t *testing.T) {
+		globDir := path.Join(testDir, "filer-glob")
+		spec := make(TreeSpec)
+		for _, name := range []string{"x.txt", "y.md", "a/c.txt", "a/b/c.txt", "a/b/d.txt", "a/e/c.txt"} {
+			spec[name] = TreeEntry{Content: name}
+		}
+		err := BuildTree(s.FS, globDir, spec)
+		if err != nil {
+			t.Fatalf("BuildTree(%q): %s", globDir, err)
+		}
+
+		fsys, err := IOFS(s.FS, globDir)
+		if err != nil {
+			t.Fatalf("IOFS(%q): %s", globDir, err)
+		}
+		gfs, ok := fsys.(fs.GlobFS)
+		if !ok {
+			s.skip(t, fmt.Sprintf("%T does not implement fs.GlobFS", fsys))
+		}
+
+		for _, tc := range []struct {
+			pattern string
+			want    []string
+		}{
+			{"*.txt", []string{"x.txt"}},
+			{"a/*/c.txt", []string{"a/b/c.txt", "a/e/c.txt"}},
+			{"*.go", nil},
+		} {
+			got, err := gfs.Glob(tc.pattern)
+			if err != nil {
+				t.Errorf("Glob(%q): %s", tc.pattern, err)
+				continue
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("Glob(%q) = %q, want %q", tc.pattern, got, tc.want)
+			}
+		}
+	})
+	s.run(t, "StatFS", func(t *testing.T) {
+		var missing []string
+		if _, ok := fsys.(fs.ReadFileFS); !ok {
+			missing = append(missing, "fs.ReadFileFS")
+		}
+		if _, ok := fsys.(fs.ReadDirFS); !ok {
+			missing = append(missing, "fs.ReadDirFS")
+		}
+		if _, ok := fsys.(fs.GlobFS); !ok {
+			missing = append(missing, "fs.GlobFS")
+		}
+		if _, ok := fsys.(fs.SubFS); !ok {
+			missing = append(missing, "fs.SubFS")
+		}
+		if len(missing) > 0 {
+			t.Logf("%T does not implement %s", fsys, strings.Join(missing, ", "))
+		}
+
+		sfs, ok := fsys.(fs.StatFS)
+		if !ok {
+			t.Fatalf("%T does not implement fs.StatFS", fsys)
+		}
+		for _, name := range []string{".", "a.txt", "b", "b/c.txt"} {
+			got, err := sfs.Stat(name)
+			if err != nil {
+				t.Errorf("StatFS.Stat(%q): %s", name, err)
+				continue
+			}
+			want, err := s.FS.Stat(path.Join(dir, name))
+			if err != nil {
+				t.Fatalf("Stat(%q): %s", path.Join(dir, name), err)
+			}
+			if name == "." {
+				// The root of a Sub is named ".", not after the directory.
+				if !got.IsDir() {
+					t.Errorf("StatFS.Stat(%q) is not a directory", name)
+				}
+				continue
+			}
+			if err := compareInfo(got, want); err != nil {
+				t.Errorf("StatFS.Stat(%q): %s", name, err)
+			}
+		}
+	})
+	s.run(t, "SubErrors", func(t *testing.T) {
+		missing := path.Join(dir, "missing")
+		_, err := s.FS.Sub(missing)
+		if errors.Is(err, absfs.ErrNotImplemented) {
+			s.skip(t, "Sub not implemented")
+		}
+		if err == nil {
+			t.Errorf("Sub(%q) of a missing directory succeeded", missing)
+		}
+
+		file := path.Join(dir, "a.txt")
+		_, err = s.FS.Sub(file)
+		if err == nil {
+			t.Errorf("Sub(%q) of a file succeeded", file)
+		}
+
+		sub, err := s.FS.Sub(path.Join(dir, "b"))
+		if err != nil {
+			t.Fatalf("Sub(%q): %s", path.Join(dir, "b"), err)
+		}
+		data, err := fs.ReadFile(sub, "../a.txt")
+		if err == nil {
+			t.Errorf("ReadFile(%q) through Sub escaped the sub tree, read %q", "../a.txt", data)
+		}
+	})
+	s.run(t, "SubSandbox", func(t *testing.T) {
+		base := path.Join(dir, "sandbox")
+		sentinel := path.Join(dir, "sentinel")
+		s.mkdir(t, path.Join(base, "x"))
+		s.writeFile(t, sentinel, []byte("sentinel"))
+
+		sub, err := s.FS.Sub(base)
+		if errors.Is(err, absfs.ErrNotImplemented) {
+			s.skip(t, "Sub not implemented")
+		}
+		if err != nil {
+			t.Fatalf("Sub(%q): %s", base, err)
+		}
+
+		// None of these names is a file inside base, so any that can be
+		// opened has escaped it.
+		for _, name := range []string{
+			"../sentinel",
+			"./../sentinel",
+			"x/../../sentinel",
+			"x/../../sandbox/../sentinel",
+			sentinel,
+			strings.TrimPrefix(sentinel, "/"),
+		} {
+			data, err := fs.ReadFile(sub, name)
+			if err == nil {
+				t.Errorf("ReadFile(%q) through Sub(%q) = %q, want error", name, base, data)
+			}
+			f, err := sub.Open(name)
+			if err == nil {
+				f.Close()
+				t.Errorf("Open(%q) through Sub(%q) succeeded, want error", name, base)
+			}
+			if sfs, ok := sub.(fs.StatFS); ok {
+				_, err = sfs.Stat(name)
+				if err == nil {
+					t.Errorf("Stat(%q) through Sub(%q) succeeded, want error", name, base)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package fstesting
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/absfs/absfs"
+)
+
+// SameFile reports whether a and b name the same file in fs. FileSystems can
+// decide by implementing SameFile(fi1, fi2 os.FileInfo) bool. Otherwise the
+// FileInfos are compared with os.SameFile, and failing that by the device
+// and inode numbers in FileInfo.Sys(). The error is non-nil if either name
+// can not be stat'ed.
+func SameFile(fs absfs.FileSystem, a, b string) (bool, error) {
+	fi1, err := fs.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	fi2, err := fs.Stat(b)
+	if err != nil {
+		return false, err
+	}
+
+	if sf, ok := fs.(interface {
+		SameFile(fi1, fi2 os.FileInfo) bool
+	}); ok {
+		return sf.SameFile(fi1, fi2), nil
+	}
+	if os.SameFile(fi1, fi2) {
+		return true, nil
+	}
+	dev1, ok1 := sysInt(fi1, "Dev")
+	dev2, ok2 := sysInt(fi2, "Dev")
+	ino1, ok3 := sysInt(fi1, "Ino")
+	ino2, ok4 := sysInt(fi2, "Ino")
+	if ok1 && ok2 && ok3 && ok4 {
+		return dev1 == dev2 && ino1 == ino2, nil
+	}
+	return false, nil
+}
+
+// testHardLinks checks that a hard link is the same file as its original and
+// that the file outlives the removal of either name.
+func (s *Suite) testHardLinks(t *testing.T, testDir string) {
+	lfs, ok := s.FS.(LinkFileSystem)
+	if !ok {
+		s.skip(t, fmt.Sprintf("%T does not implement LinkFileSystem", s.FS))
+	}
+	name := path.Join(testDir, "original")
+	link := path.Join(testDir, "link")
+	other := path.Join(testDir, "other")
+	s.writeFile(t, name, []byte("original"))
+	s.writeFile(t, other, []byte("original"))
+
+	err := lfs.Link(name, link)
+	if err != nil {
+		t.Fatalf("Link(%q, %q): %s", name, link, err)
+	}
+	s.checkSameFile(t, name, link, true)
+	s.checkSameFile(t, name, other, false)
+	s.checkNlink(t, name, 2)
+
+	s.writeFile(t, link, []byte("written through link"))
+	s.checkContent(t, name, "written through link")
+
+	err = lfs.Link(name, other)
+	if err == nil {
+		t.Errorf("Link(%q, %q) over an existing file succeeded", name, other)
+	} else {
+		checkLinkError(t, "Link", err, syscall.EEXIST)
+	}
+
+	err = s.FS.Remove(name)
+	if err != nil {
+		t.Fatalf("Remove(%q): %s", name, err)
+	}
+	s.checkContent(t, link, "written through link")
+	s.checkNlink(t, link, 1)
+}
+
+// checkSameFile fails the test unless SameFile(s.FS, a, b) returns want.
+func (s *Suite) checkSameFile(t *testing.T, a, b string, want bool) {
+	t.Helper()
+	same, err := SameFile(s.FS, a, b)
+	if err != nil {
+		t.Fatalf("SameFile(%q, %q): %s", a, b, err)
+	}
+	if same != want {
+		t.Errorf("SameFile(%q, %q) = %t, want %t", a, b, same, want)
+	}
+}
+
+// checkNlink fails the test if FileInfo.Sys() of name reports a link count
+// other than want. Nothing is checked if no count is reported.
+func (s *Suite) checkNlink(t *testing.T, name string, want int64) {
+	t.Helper()
+	info, err := s.FS.Stat(name)
+	if err != nil {
+		t.Fatalf("Stat(%q): %s", name, err)
+	}
+	nlink, ok := sysInt(info, "Nlink", "NumberOfLinks")
+	if ok && nlink != want {
+		t.Errorf("Stat(%q) link count = %d, want %d", name, nlink, want)
+	}
+}
@@ -0,0 +1,801 @@
+package fstesting
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// testSparseFiles writes a few bytes far past the end of an empty file and
+// checks that the hole reads back as zeros. Where the FileSystem reports block
+// usage the hole must not have been materialized.
+func (s *Suite) testSparseFiles(t *testing.T, testDir string) {
+	const offset = 1 << 20
+	name := path.Join(testDir, "sparse")
+	data := []byte("sparse")
+
+	f, err := s.FS.Create(name)
+	if err != nil {
+		t.Fatalf("Create(%q): %s", name, err)
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		t.Fatalf("Seek(%d): %s", offset, err)
+	}
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		t.Fatalf("Write: %s", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	info, err := s.FS.Stat(name)
+	if err != nil {
+		t.Fatalf("Stat(%q): %s", name, err)
+	}
+	if info.Size() != offset+int64(len(data)) {
+		t.Errorf("Size() = %d, want %d", info.Size(), offset+len(data))
+	}
+
+	f, err = s.FS.Open(name)
+	if err != nil {
+		t.Fatalf("Open(%q): %s", name, err)
+	}
+	defer f.Close()
+	hole := make([]byte, offset)
+	_, err = io.ReadFull(f, hole)
+	if err != nil {
+		t.Fatalf("reading hole: %s", err)
+	}
+	for i, b := range hole {
+		if b != 0 {
+			t.Errorf("hole byte %d = %#x, want 0", i, b)
+			break
+		}
+	}
+
+	// Blocks is in 512 byte units for syscall.Stat_t on every platform that
+	// has it.
+	blocks, ok := sysInt(info, "Blocks")
+	if !ok {
+		t.Log("block usage not reported, checked logical size only")
+		return
+	}
+	if blocks*512 >= offset {
+		t.Errorf("%d blocks allocated for a %d byte hole", blocks, offset)
+	}
+}
+
+// testLargeFiles grows a file past 2GB without writing the payload and checks
+// that sizes and offsets beyond 32 bits survive the round trip.
+func (s *Suite) testLargeFiles(t *testing.T, testDir string) {
+	if testing.Short() {
+		s.skip(t, "skipping large file test in short mode")
+	}
+	const offset = 1<<31 + 4096
+	name := path.Join(testDir, "large")
+	data := []byte("large")
+
+	f, err := s.FS.Create(name)
+	if err != nil {
+		t.Fatalf("Create(%q): %s", name, err)
+	}
+	err = f.Truncate(offset)
+	if err != nil {
+		f.Close()
+		t.Fatalf("Truncate(%d): %s", offset, err)
+	}
+	n, err := f.Seek(offset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		t.Fatalf("Seek(%d): %s", offset, err)
+	}
+	if n != offset {
+		t.Errorf("Seek(%d) = %d", offset, n)
+	}
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		t.Fatalf("Write: %s", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	info, err := s.FS.Stat(name)
+	if err != nil {
+		t.Fatalf("Stat(%q): %s", name, err)
+	}
+	if info.Size() != offset+int64(len(data)) {
+		t.Errorf("Size() = %d, want %d", info.Size(), offset+len(data))
+	}
+
+	f, err = s.FS.Open(name)
+	if err != nil {
+		t.Fatalf("Open(%q): %s", name, err)
+	}
+	defer f.Close()
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		t.Fatalf("Seek(%d): %s", offset, err)
+	}
+	buf := make([]byte, len(data))
+	_, err = io.ReadFull(f, buf)
+	if err != nil {
+		t.Fatalf("Read at %d: %s", offset, err)
+	}
+	if string(buf) != string(data) {
+		t.Errorf("Read at %d = %q, want %q", offset, buf, data)
+	}
+}
+
+// testCaseSensitivity checks that name lookups follow Features.CaseSensitive.
+func (s *Suite) testCaseSensitivity(t *testing.T, testDir string) {
+	name := path.Join(testDir, "File.txt")
+	lower := path.Join(testDir, "file.txt")
+	upper := path.Join(testDir, "FILE.TXT")
+	data := []byte("case sensitivity")
+	s.writeFile(t, name, data)
+
+	if s.Features.CaseSensitive {
+		f, err := s.FS.Open(lower)
+		if err == nil {
+			f.Close()
+			t.Fatalf("Open(%q) succeeded after creating %q on a case sensitive filesystem", lower, name)
+		}
+		if !os.IsNotExist(err) {
+			t.Errorf("Open(%q) error = %s, want not exist", lower, err)
+		}
+		return
+	}
+
+	f, err := s.FS.Open(lower)
+	if err != nil {
+		t.Fatalf("Open(%q): %s", lower, err)
+	}
+	got, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("Read(%q): %s", lower, err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("Read(%q) = %q, want %q", lower, got, data)
+	}
+
+	want, err := s.FS.Stat(name)
+	if err != nil {
+		t.Fatalf("Stat(%q): %s", name, err)
+	}
+	info, err := s.FS.Stat(upper)
+	if err != nil {
+		t.Fatalf("Stat(%q): %s", upper, err)
+	}
+	if info.Size() != want.Size() {
+		t.Errorf("Stat(%q).Size() = %d, want %d", upper, info.Size(), want.Size())
+	}
+	ino1, ok1 := sysInt(want, "Ino")
+	ino2, ok2 := sysInt(info, "Ino")
+	if ok1 && ok2 && ino1 != ino2 {
+		t.Errorf("%q and %q have different inodes %d != %d", name, upper, ino1, ino2)
+	}
+}
+
+// testAtomicRename checks that a rename over an existing target never leaves
+// the target missing or partially written, whether racing with readers or
+// interrupted by a crash.
+func (s *Suite) testAtomicRename(t *testing.T, testDir string) {
+	s.run(t, "Concurrent", func(t *testing.T) {
+		s.testConcurrentRename(t, testDir)
+	})
+	s.run(t, "Crash", func(t *testing.T) {
+		s.testRenameCrash(t, testDir)
+	})
+}
+
+// testConcurrentRename has ConcurrencyLevel writers repeatedly rename complete
+// files over a shared target while a reader checks that every read of the
+// target returns exactly one writer's payload.
+func (s *Suite) testConcurrentRename(t *testing.T, testDir string) {
+	if s.ConcurrencyLevel <= 0 {
+		s.skip(t, "ConcurrencyLevel is 0")
+	}
+	writers := s.ConcurrencyLevel
+	const iterations = 50
+	target := path.Join(testDir, "target")
+
+	payloads := make(map[string]int)
+	for i := 0; i < writers; i++ {
+		payloads[strings.Repeat(string(rune('a'+i)), 4096)] = i
+	}
+	s.writeFile(t, target, []byte(strings.Repeat("a", 4096)))
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte(strings.Repeat(string(rune('a'+i)), 4096))
+			for j := 0; j < iterations; j++ {
+				tmp := path.Join(testDir, fmt.Sprintf("tmp%d-%d", i, j))
+				err := writeAll(s.FS, tmp, data)
+				if err != nil {
+					t.Errorf("writer %d: %s", i, err)
+					return
+				}
+				err = s.FS.Rename(tmp, target)
+				if err != nil {
+					t.Errorf("writer %d: Rename(%q, %q): %s", i, tmp, target, err)
+					return
+				}
+			}
+		}(i)
+	}
+
+	reads := 0
+	var readerErr error
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			data, err := s.FS.ReadFile(target)
+			if err != nil {
+				readerErr = fmt.Errorf("ReadFile(%q): %s", target, err)
+				return
+			}
+			if _, ok := payloads[string(data)]; !ok {
+				readerErr = fmt.Errorf("read %d bytes that match no writer's payload", len(data))
+				return
+			}
+			reads++
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	<-readerDone
+	if readerErr != nil {
+		t.Fatal(readerErr)
+	}
+	t.Logf("%d reads of %q during %d renames", reads, target, writers*iterations)
+}
+
+// testRenameCrash fails renames over a target through an InjectFS, before and
+// after the rename reaches the FileSystem, and checks that the target is left
+// holding either all of its old contents or all of the new.
+func (s *Suite) testRenameCrash(t *testing.T, testDir string) {
+	oldData := strings.Repeat("o", 4096)
+	newData := strings.Repeat("n", 4096)
+	for _, crash := range []struct {
+		name  string
+		point CrashPoint
+	}{{"before", CrashBefore}, {"after", CrashAfter}} {
+		target := path.Join(testDir, "crash-"+crash.name)
+		tmp := target + ".tmp"
+		s.writeFile(t, target, []byte(oldData))
+		s.writeFile(t, tmp, []byte(newData))
+
+		ifs := NewInjectFS(s.FS)
+		ifs.CrashRename(path.Base(tmp), crash.point, syscall.EIO)
+		err := ifs.Rename(tmp, target)
+		if err == nil {
+			t.Fatalf("Rename(%q, %q) with an injected crash succeeded", tmp, target)
+		}
+
+		data, err := s.FS.ReadFile(target)
+		if err != nil {
+			t.Errorf("ReadFile(%q) after crashed Rename: %s", target, err)
+			continue
+		}
+		switch string(data) {
+		case oldData:
+			t.Logf("crash %s Rename left the old contents", crash.name)
+		case newData:
+			t.Logf("crash %s Rename left the new contents", crash.name)
+		default:
+			t.Errorf("ReadFile(%q) after crashed Rename returned %d bytes matching neither the old nor the new contents", target, len(data))
+		}
+	}
+}
+
+// testOwnership chowns a file to the current uid and gid, which is permitted
+// without privileges, and checks the ownership reported by FileInfo.Sys().
+func (s *Suite) testOwnership(t *testing.T, testDir string) {
+	uid, gid := os.Getuid(), os.Getgid()
+	if uid < 0 || gid < 0 {
+		s.skip(t, "ownership not available on this platform")
+	}
+	name := path.Join(testDir, "owned")
+	s.writeFile(t, name, []byte("owned"))
+
+	err := s.FS.Chown(name, uid, gid)
+	if errors.Is(err, os.ErrPermission) {
+		s.skip(t, fmt.Sprintf("Chown not permitted: %s", err))
+	}
+	if err != nil {
+		t.Fatalf("Chown(%q, %d, %d): %s", name, uid, gid, err)
+	}
+
+	info, err := s.FS.Stat(name)
+	if err != nil {
+		t.Fatalf("Stat(%q): %s", name, err)
+	}
+	gotUID, ok1 := sysInt(info, "Uid")
+	gotGID, ok2 := sysInt(info, "Gid")
+	if !ok1 || !ok2 {
+		s.skip(t, fmt.Sprintf("ownership not reported by FileInfo.Sys() of type %T", info.Sys()))
+	}
+	if gotUID != int64(uid) || gotGID != int64(gid) {
+		t.Errorf("owner = %d:%d, want %d:%d", gotUID, gotGID, uid, gid)
+	}
+}
+
+// testXattrs sets, reads, lists and removes a user extended attribute.
+func (s *Suite) testXattrs(t *testing.T, testDir string) {
+	xfs, ok := s.FS.(XattrFileSystem)
+	if !ok {
+		s.skip(t, fmt.Sprintf("%T does not implement XattrFileSystem", s.FS))
+	}
+	name := path.Join(testDir, "xattrs")
+	attr := "user.fstesting"
+	value := []byte("extended attribute")
+	s.writeFile(t, name, []byte("xattrs"))
+
+	err := xfs.Setxattr(name, attr, value)
+	if err != nil {
+		t.Fatalf("Setxattr(%q, %q): %s", name, attr, err)
+	}
+	got, err := xfs.Getxattr(name, attr)
+	if err != nil {
+		t.Fatalf("Getxattr(%q, %q): %s", name, attr, err)
+	}
+	if string(got) != string(value) {
+		t.Errorf("Getxattr(%q, %q) = %q, want %q", name, attr, got, value)
+	}
+
+	attrs, err := xfs.Listxattr(name)
+	if err != nil {
+		t.Fatalf("Listxattr(%q): %s", name, err)
+	}
+	found := false
+	for _, a := range attrs {
+		found = found || a == attr
+	}
+	if !found {
+		t.Errorf("Listxattr(%q) = %q, missing %q", name, attrs, attr)
+	}
+
+	err = xfs.Removexattr(name, attr)
+	if err != nil {
+		t.Fatalf("Removexattr(%q, %q): %s", name, attr, err)
+	}
+	_, err = xfs.Getxattr(name, attr)
+	if err == nil {
+		t.Errorf("Getxattr(%q, %q) succeeded after Removexattr", name, attr)
+	}
+	attrs, err = xfs.Listxattr(name)
+	if err != nil {
+		t.Fatalf("Listxattr(%q): %s", name, err)
+	}
+	for _, a := range attrs {
+		if a == attr {
+			t.Errorf("Listxattr(%q) = %q after removing %q", name, attrs, attr)
+		}
+	}
+}
+
+// testSnapshots checks that restoring a snapshot undoes changes made after it
+// was taken.
+func (s *Suite) testSnapshots(t *testing.T, testDir string) {
+	sfs, ok := s.FS.(SnapshotFileSystem)
+	if !ok {
+		s.skip(t, fmt.Sprintf("%T does not implement SnapshotFileSystem", s.FS))
+	}
+	dir := path.Join(testDir, "snapshots")
+	name, added := path.Join(dir, "file"), path.Join(dir, "added")
+	s.mkdir(t, dir)
+	s.writeFile(t, name, []byte("original"))
+
+	id, err := sfs.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %s", err)
+	}
+	s.writeFile(t, name, []byte("overwritten"))
+	s.writeFile(t, added, []byte("added"))
+
+	err = sfs.Restore(id)
+	if err != nil {
+		t.Fatalf("Restore(%q): %s", id, err)
+	}
+	s.checkContent(t, name, "original")
+	_, err = s.FS.Stat(added)
+	if !os.IsNotExist(err) {
+		t.Errorf("Stat(%q) after Restore error = %v, want not exist", added, err)
+	}
+}
+
+// testSpaceReporting checks that Statfs reports consistent numbers and that
+// writing a file uses up free space.
+func (s *Suite) testSpaceReporting(t *testing.T, testDir string) {
+	sfs, ok := s.FS.(StatfsFileSystem)
+	if !ok {
+		s.skip(t, fmt.Sprintf("%T does not implement StatfsFileSystem", s.FS))
+	}
+	const size = 1 << 20
+	name := path.Join(testDir, "statfs")
+
+	before, err := sfs.Statfs(testDir)
+	if err != nil {
+		t.Fatalf("Statfs(%q): %s", testDir, err)
+	}
+	if before.Free > before.Total || before.Available > before.Free {
+		t.Errorf("Statfs(%q) = %+v, want Available <= Free <= Total", testDir, before)
+	}
+
+	// Random data defeats compressing or deduplicating FileSystems.
+	data := make([]byte, size)
+	s.rand(0).Read(data)
+	f, err := s.FS.Create(name)
+	if err != nil {
+		t.Fatalf("Create(%q): %s", name, err)
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		t.Fatalf("writing %q: %s", name, err)
+	}
+
+	after, err := sfs.Statfs(testDir)
+	if err != nil {
+		t.Fatalf("Statfs(%q): %s", testDir, err)
+	}
+	// Block rounding and metadata can only add to the space used.
+	used := int64(before.Free) - int64(after.Free)
+	if used < size {
+		t.Errorf("free space decreased by %d bytes after writing %d", used, size)
+	}
+}
+
+// testDurability writes a file through an InjectFS that holds writes until
+// they are synced, syncs part of the data, writes more and then simulates a
+// crash that discards the unsynced writes. After reopening the FileSystem
+// the file must hold exactly the synced data.
+func (s *Suite) testDurability(t *testing.T, testDir string) {
+	if s.Reopen == nil {
+		s.skip(t, "Features.Durable is set but Reopen is not")
+	}
+	name := path.Join(testDir, "durable")
+	synced := strings.Repeat("s", 8192)
+	unsynced := strings.Repeat("u", 8192)
+
+	ifs := NewInjectFS(s.FS)
+	ifs.DropUnsynced(path.Base(name))
+	f, err := ifs.Create(name)
+	if err != nil {
+		t.Fatalf("Create(%q): %s", name, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	_, err = f.Write([]byte(synced))
+	if err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("Sync: %s", err)
+	}
+	// On POSIX systems the new directory entry is only durable once the
+	// directory itself is synced. FileSystems that can not open or sync a
+	// directory are expected to make entries durable without it.
+	if d, err := s.FS.Open(testDir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	_, err = f.Write([]byte(unsynced))
+	if err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	ifs.Crash()
+
+	fs, err := s.Reopen()
+	if err != nil {
+		t.Fatalf("Reopen: %s", err)
+	}
+	data, err := fs.ReadFile(name)
+	if err != nil {
+		t.Fatalf("ReadFile(%q) after crash and Reopen: %s", name, err)
+	}
+	if string(data) != synced {
+		t.Errorf("%q after crash and Reopen holds %d bytes, want the %d synced bytes", name, len(data), len(synced))
+	}
+}
+
+// testNoSpace fills a FileSystem of MaxBytes capacity and checks that the
+// write that runs out of space fails with ENOSPC, reports how much it wrote,
+// and leaves the file holding exactly the data written.
+func (s *Suite) testNoSpace(t *testing.T, testDir string) {
+	const chunkSize = 64 << 10
+	name := path.Join(testDir, "nospace")
+	data := make([]byte, chunkSize)
+	s.rand(0).Read(data)
+
+	f, err := s.FS.Create(name)
+	if err != nil {
+		t.Fatalf("Create(%q): %s", name, err)
+	}
+	defer s.FS.Remove(name)
+
+	var written int64
+	limit := 2*s.MaxBytes + chunkSize
+	for written <= limit {
+		var n int
+		n, err = f.Write(data)
+		if n < 0 || n > len(data) {
+			t.Fatalf("Write returned %d for a %d byte write", n, len(data))
+		}
+		written += int64(n)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		f.Close()
+		t.Fatalf("wrote %d bytes to a FileSystem of %d bytes without error", written, s.MaxBytes)
+	}
+	checkPathError(t, "Write", err, syscall.ENOSPC)
+	f.Close()
+
+	info, err := s.FS.Stat(name)
+	if err != nil {
+		t.Fatalf("Stat(%q): %s", name, err)
+	}
+	if info.Size() != written {
+		t.Errorf("Stat(%q).Size() = %d, want the %d bytes written", name, info.Size(), written)
+	}
+	got, err := s.FS.ReadFile(name)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %s", name, err)
+	}
+	for off := 0; off < len(got); off += chunkSize {
+		end := off + chunkSize
+		if end > len(got) {
+			end = len(got)
+		}
+		if string(got[off:end]) != string(data[:end-off]) {
+			t.Errorf("content of %q differs from the data written at offset %d", name, off)
+			break
+		}
+	}
+}
+
+// testReflink clones a file and checks that the clone starts with the same
+// contents and that writes to either file do not show through to the other.
+func (s *Suite) testReflink(t *testing.T, testDir string) {
+	cfs, ok := s.FS.(CloneFileSystem)
+	if !ok {
+		s.skip(t, fmt.Sprintf("%T does not implement CloneFileSystem", s.FS))
+	}
+	src := path.Join(testDir, "src")
+	dst := path.Join(testDir, "clone")
+	data := strings.Repeat("reflink ", 1024)
+	s.writeFile(t, src, []byte(data))
+
+	err := cfs.Clone(src, dst)
+	if err != nil {
+		t.Fatalf("Clone(%q, %q): %s", src, dst, err)
+	}
+	s.checkContent(t, dst, data)
+
+	f, err := s.FS.OpenFile(dst, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile(%q, O_WRONLY): %s", dst, err)
+	}
+	_, err = f.WriteAt([]byte("CLONE"), 0)
+	f.Close()
+	if err != nil {
+		t.Fatalf("WriteAt: %s", err)
+	}
+	s.checkContent(t, dst, "CLONE"+data[5:])
+	s.checkContent(t, src, data)
+
+	s.writeFile(t, src, []byte("changed"))
+	s.checkContent(t, dst, "CLONE"+data[5:])
+}
+
+// testMemoryMapped checks that a mapped file holds the file's contents and,
+// if writable mappings are supported, that changes made through one persist.
+func (s *Suite) testMemoryMapped(t *testing.T, testDir string) {
+	mfs, ok := s.FS.(MmapFileSystem)
+	if !ok {
+		s.skip(t, fmt.Sprintf("%T does not implement MmapFileSystem", s.FS))
+	}
+	name := path.Join(testDir, "mmap")
+	s.writeFile(t, name, []byte("memory mapped"))
+
+	data, unmap, err := mfs.Map(name, os.O_RDONLY)
+	if err != nil {
+		t.Fatalf("Map(%q, O_RDONLY): %s", name, err)
+	}
+	if string(data) != "memory mapped" {
+		t.Errorf("Map(%q, O_RDONLY) = %q, want %q", name, data, "memory mapped")
+	}
+	err = unmap()
+	if err != nil {
+		t.Fatalf("unmap: %s", err)
+	}
+
+	data, unmap, err = mfs.Map(name, os.O_RDWR)
+	if err != nil {
+		t.Logf("Map(%q, O_RDWR): %s, skipping writable mappings", name, err)
+		return
+	}
+	copy(data, "MEMORY")
+	err = unmap()
+	if err != nil {
+		t.Fatalf("unmap: %s", err)
+	}
+	s.checkContent(t, name, "MEMORY mapped")
+}
+
+// testSpecialFiles creates and removes a named pipe and a character device.
+// FileSystems without Features.SpecialFiles must refuse to create them with
+// ENOTSUP.
+func (s *Suite) testSpecialFiles(t *testing.T, testDir string) {
+	sfs, ok := s.FS.(SpecialFileSystem)
+	if !ok {
+		s.skip(t, fmt.Sprintf("%T does not implement SpecialFileSystem", s.FS))
+	}
+	fifo := path.Join(testDir, "fifo")
+	dev := path.Join(testDir, "dev")
+
+	if !s.Features.SpecialFiles {
+		err := sfs.Mkfifo(fifo, 0600)
+		if err == nil {
+			t.Errorf("Mkfifo(%q) succeeded without Features.SpecialFiles", fifo)
+		} else {
+			checkPathError(t, "Mkfifo", err, syscall.ENOTSUP)
+		}
+		err = sfs.Mknod(dev, os.ModeDevice|os.ModeCharDevice|0600, 0)
+		if err == nil {
+			t.Errorf("Mknod(%q) succeeded without Features.SpecialFiles", dev)
+		} else {
+			checkPathError(t, "Mknod", err, syscall.ENOTSUP)
+		}
+		return
+	}
+
+	s.run(t, "Fifo", func(t *testing.T) {
+		err := sfs.Mkfifo(fifo, 0600)
+		if err != nil {
+			t.Fatalf("Mkfifo(%q): %s", fifo, err)
+		}
+		s.checkSpecial(t, fifo, os.ModeNamedPipe)
+	})
+	s.run(t, "CharDevice", func(t *testing.T) {
+		err := sfs.Mknod(dev, os.ModeDevice|os.ModeCharDevice|0600, 0)
+		if errors.Is(err, os.ErrPermission) {
+			s.skip(t, fmt.Sprintf("Mknod not permitted: %s", err))
+		}
+		if err != nil {
+			t.Fatalf("Mknod(%q): %s", dev, err)
+		}
+		s.checkSpecial(t, dev, os.ModeDevice|os.ModeCharDevice)
+	})
+}
+
+// checkSpecial checks that Stat reports the type bits typ for name, and that
+// name can then be removed.
+func (s *Suite) checkSpecial(t *testing.T, name string, typ os.FileMode) {
+	t.Helper()
+	info, err := s.FS.Stat(name)
+	if err != nil {
+		t.Fatalf("Stat(%q): %s", name, err)
+	}
+	if info.Mode().Type() != typ {
+		t.Errorf("Stat(%q).Mode() = %s, want type %s", name, info.Mode(), typ)
+	}
+
+	err = s.FS.Remove(name)
+	if err != nil {
+		t.Fatalf("Remove(%q): %s", name, err)
+	}
+	_, err = s.FS.Stat(name)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat(%q) after Remove = %v, want ErrNotExist", name, err)
+	}
+}
+
+// testLocking checks that an exclusive lock held through one File makes
+// TryLock fail and Lock wait on a second File of the same name until it is
+// released.
+func (s *Suite) testLocking(t *testing.T, testDir string) {
+	name := path.Join(testDir, "lock")
+	s.writeFile(t, name, []byte("lock"))
+
+	open := func() (absfs.File, LockingFile) {
+		f, err := s.FS.OpenFile(name, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("OpenFile(%q, O_RDWR): %s", name, err)
+		}
+		t.Cleanup(func() { f.Close() })
+		lf, ok := f.(LockingFile)
+		if !ok {
+			s.skip(t, fmt.Sprintf("%T does not implement LockingFile", f))
+		}
+		return f, lf
+	}
+	firstFile, first := open()
+	_, second := open()
+
+	err := first.Lock()
+	if err != nil {
+		t.Fatalf("Lock: %s", err)
+	}
+	ok, err := second.TryLock()
+	if err != nil {
+		t.Fatalf("TryLock: %s", err)
+	}
+	if ok {
+		t.Fatal("TryLock succeeded while another File held the lock")
+	}
+
+	locked := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		locked <- second.Lock()
+	}()
+	// Closing the first File drops its lock even if Unlock fails, so the
+	// waiting Lock returns however the test ends.
+	t.Cleanup(func() {
+		firstFile.Close()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Error("Lock still waiting 5s after the locking File was closed")
+		}
+	})
+	select {
+	case err := <-locked:
+		t.Fatalf("Lock returned %v while another File held the lock", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	err = first.Unlock()
+	if err != nil {
+		t.Fatalf("Unlock: %s", err)
+	}
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Fatalf("Lock after Unlock: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lock still waiting 5s after the lock was released")
+	}
+	err = second.Unlock()
+	if err != nil {
+		t.Fatalf("Unlock: %s", err)
+	}
+}
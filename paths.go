@@ -0,0 +1,159 @@
+package fstesting
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// testPathHandling covers how names and paths are resolved.
+func (s *Suite) testPathHandling(t *testing.T, testDir string) {
+	dir := path.Join(testDir, "paths")
+	s.mkdir(t, dir)
+
+	s.run(t, "LengthLimits", func(t *testing.T) {
+		maxName, maxPath := s.MaxNameLen, s.MaxPathLen
+		if maxName <= 0 {
+			maxName = 255
+		}
+		if maxPath <= 0 {
+			maxPath = 4096
+		}
+
+		for _, n := range []int{maxName, maxName + 1} {
+			s.checkLongPath(t, path.Join(dir, strings.Repeat("n", n)))
+		}
+
+		for _, n := range []int{maxPath, maxPath + 1} {
+			if n <= len(dir)+1 {
+				s.skip(t, fmt.Sprintf("test directory %q is longer than the %d byte path limit", dir, n))
+			}
+			name := longPath(dir, n)
+			err := s.FS.MkdirAll(path.Dir(name), 0777)
+			if err != nil {
+				checkPathError(t, "MkdirAll", err, syscall.ENAMETOOLONG)
+				continue
+			}
+			s.checkLongPath(t, name)
+		}
+	})
+
+	s.run(t, "EmptyAndRoot", func(t *testing.T) {
+		f, err := s.FS.Open("")
+		if err == nil {
+			f.Close()
+			t.Error(`Open("") succeeded, want error`)
+		} else if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrInvalid) && !errors.Is(err, syscall.EINVAL) {
+			t.Errorf(`Open("") error = %s, want ENOENT or EINVAL`, err)
+		}
+
+		for _, name := range []string{"/", "."} {
+			info, err := s.FS.Stat(name)
+			if err != nil {
+				t.Errorf("Stat(%q): %s", name, err)
+				continue
+			}
+			if !info.IsDir() {
+				t.Errorf("Stat(%q).IsDir() = false, want true", name)
+			}
+		}
+
+		f, err = s.FS.Create("/")
+		if err == nil {
+			f.Close()
+			t.Error(`Create("/") succeeded, want error`)
+		}
+	})
+
+	s.run(t, "ParentNotDir", func(t *testing.T) {
+		parent := path.Join(dir, "a")
+		child := path.Join(parent, "b")
+		s.writeFile(t, parent, []byte("a"))
+
+		f, err := s.FS.Create(child)
+		if err == nil {
+			f.Close()
+			t.Errorf("Create(%q) succeeded with a regular file as parent", child)
+		} else {
+			checkPathError(t, "Create", err, syscall.ENOTDIR)
+		}
+
+		err = s.FS.MkdirAll(child, 0777)
+		if err == nil {
+			t.Errorf("MkdirAll(%q) succeeded with a regular file as parent", child)
+		} else {
+			checkPathError(t, "MkdirAll", err, syscall.ENOTDIR)
+		}
+	})
+}
+
+// checkLongPath creates name, which may exceed the limits of the FileSystem.
+// Creation must either succeed without truncating the name, or fail with
+// ENAMETOOLONG.
+func (s *Suite) checkLongPath(t *testing.T, name string) {
+	t.Helper()
+	data := []byte("long path")
+	err := writeAll(s.FS, name, data)
+	if err != nil {
+		checkPathError(t, "Create", err, syscall.ENAMETOOLONG)
+		return
+	}
+
+	entries, err := s.FS.ReadDir(path.Dir(name))
+	if err != nil {
+		t.Fatalf("ReadDir of parent of %d byte path: %s", len(name), err)
+	}
+	found := false
+	for _, e := range entries {
+		found = found || e.Name() == path.Base(name)
+	}
+	if !found {
+		t.Errorf("%d byte name not listed by ReadDir, it may have been truncated", len(path.Base(name)))
+	}
+	got, err := s.FS.ReadFile(name)
+	if err != nil {
+		t.Errorf("ReadFile of %d byte path: %s", len(name), err)
+	} else if string(got) != string(data) {
+		t.Errorf("ReadFile of %d byte path = %q, want %q", len(name), got, data)
+	}
+}
+
+// checkPathError fails the test unless err is an *os.PathError wrapping one
+// of want.
+func checkPathError(t *testing.T, op string, err error, want ...syscall.Errno) {
+	t.Helper()
+	var perr *os.PathError
+	if !errors.As(err, &perr) {
+		t.Errorf("%s error = %#v, want *os.PathError", op, err)
+		return
+	}
+	for _, errno := range want {
+		if errors.Is(err, errno) {
+			return
+		}
+	}
+	t.Errorf("%s error = %s, want one of %q", op, err, want)
+}
+
+// longPath returns a path of exactly n bytes below dir made of components of
+// at most 100 bytes.
+func longPath(dir string, n int) string {
+	p := dir
+	for len(p) < n {
+		c := n - len(p) - 1
+		if c == 0 {
+			p += "p"
+			break
+		}
+		if c > 100 {
+			c = 100
+		}
+		p += "/" + strings.Repeat("p", c)
+	}
+	return p
+}
@@ -0,0 +1,121 @@
+package fstesting
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// testPermissions covers setting and reporting permission bits.
+func (s *Suite) testPermissions(t *testing.T, testDir string) {
+	dir := path.Join(testDir, "permissions")
+	s.mkdir(t, dir)
+
+	s.run(t, "ModeBits", func(t *testing.T) {
+		name := path.Join(dir, "modebits")
+		s.writeFile(t, name, []byte("mode bits"))
+
+		err := ForEveryPermission(func(mode os.FileMode) error {
+			s.checkChmod(t, name, mode)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Support for the special bits varies, so they are only reported.
+		var preserved, dropped []string
+		for _, bit := range []os.FileMode{os.ModeSetuid, os.ModeSetgid, os.ModeSticky} {
+			info, err := chmodStat(s.FS, name, bit|0755)
+			if err != nil {
+				t.Fatalf("Chmod(%q, %s): %s", name, bit|0755, err)
+			}
+			if info.Mode().Perm() != 0755 {
+				t.Errorf("Chmod(%q, %s) changed permissions to %s", name, bit|0755, info.Mode())
+			}
+			if info.Mode()&bit != 0 {
+				preserved = append(preserved, bitName(bit))
+			} else {
+				dropped = append(dropped, bitName(bit))
+			}
+		}
+		t.Logf("special bits preserved: [%s] dropped: [%s]", strings.Join(preserved, " "), strings.Join(dropped, " "))
+	})
+	s.run(t, "Umask", func(t *testing.T) {
+		probe := path.Join(dir, "umask")
+		f, err := s.FS.OpenFile(probe, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0777)
+		if err != nil {
+			t.Fatalf("OpenFile(%q, O_CREATE|O_EXCL|O_WRONLY, 0777): %s", probe, err)
+		}
+		f.Close()
+		info, err := s.FS.Stat(probe)
+		if err != nil {
+			t.Fatalf("Stat(%q): %s", probe, err)
+		}
+		umask := 0777 &^ info.Mode().Perm()
+		if s.Umask != 0 && umask != s.Umask {
+			t.Errorf("file created with mode 0777 has mode %s, want umask %04o applied", info.Mode().Perm(), s.Umask)
+		}
+		t.Logf("umask %04o", umask)
+
+		// The same mask applies to every kind of creation, but not to Chmod.
+		for i, mode := range []os.FileMode{0777, 0750, 0666, 0600} {
+			file := path.Join(dir, fmt.Sprintf("umask-file%d", i))
+			f, err := s.FS.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+			if err != nil {
+				t.Fatalf("OpenFile(%q, O_CREATE|O_EXCL|O_WRONLY, %s): %s", file, mode, err)
+			}
+			f.Close()
+			s.checkMode(t, file, mode&^umask)
+
+			sub := path.Join(dir, fmt.Sprintf("umask-dir%d", i))
+			err = s.FS.Mkdir(sub, mode)
+			if err != nil {
+				t.Fatalf("Mkdir(%q, %s): %s", sub, mode, err)
+			}
+			s.checkMode(t, sub, mode&^umask)
+		}
+		s.checkChmod(t, probe, 0777)
+	})
+}
+
+// checkChmod changes the permissions of name to mode and fails the test
+// unless Stat then reports them.
+func (s *Suite) checkChmod(t *testing.T, name string, mode os.FileMode) {
+	t.Helper()
+	info, err := chmodStat(s.FS, name, mode)
+	if err != nil {
+		t.Fatalf("Chmod(%q, %s): %s", name, mode, err)
+	}
+	if info.Mode().Perm() != mode {
+		t.Errorf("Chmod(%q, %s) then Stat = %s", name, mode, info.Mode().Perm())
+	}
+}
+
+// bitName returns the name of a special mode bit.
+func bitName(bit os.FileMode) string {
+	switch bit {
+	case os.ModeSetuid:
+		return "setuid"
+	case os.ModeSetgid:
+		return "setgid"
+	case os.ModeSticky:
+		return "sticky"
+	}
+	return bit.String()
+}
+
+// checkMode fails the test unless Stat reports the permission bits want for
+// name.
+func (s *Suite) checkMode(t *testing.T, name string, want os.FileMode) {
+	t.Helper()
+	info, err := s.FS.Stat(name)
+	if err != nil {
+		t.Fatalf("Stat(%q): %s", name, err)
+	}
+	if info.Mode().Perm() != want {
+		t.Errorf("Stat(%q) mode = %s, want %s", name, info.Mode().Perm(), want)
+	}
+}
@@ -0,0 +1,91 @@
+package fstesting
+
+import (
+	"math/rand"
+	"path"
+	"strings"
+
+	"github.com/absfs/absfs"
+)
+
+// TreeParams controls the shape of the trees made by GenerateRandomTree.
+// Zero fields take the defaults given.
+type TreeParams struct {
+	MaxDepth    int    // levels of directories below the root, default 3
+	MaxFanOut   int    // entries in each directory, default 5
+	MaxFileSize int    // bytes in each file, default 4096
+	MaxNameLen  int    // characters in each name, default 12
+	NameChars   string // characters names are made of, default lower case ASCII letters, digits and some non-ASCII letters
+}
+
+// defaultNameChars avoids upper case letters so that generated names don't
+// collide on case insensitive FileSystems.
+const defaultNameChars = "abcdefghijklmnopqrstuvwxyz0123456789-_.äéñøß日本語"
+
+func (p TreeParams) withDefaults() TreeParams {
+	if p.MaxDepth == 0 {
+		p.MaxDepth = 3
+	}
+	if p.MaxFanOut == 0 {
+		p.MaxFanOut = 5
+	}
+	if p.MaxFileSize == 0 {
+		p.MaxFileSize = 4096
+	}
+	if p.MaxNameLen == 0 {
+		p.MaxNameLen = 12
+	}
+	if p.NameChars == "" {
+		p.NameChars = defaultNameChars
+	}
+	return p
+}
+
+// GenerateRandomTree creates a random tree of directories and files of random
+// content under root in fs and returns a manifest of what it created. The
+// tree depends only on the state of rng and params, so a failure can be
+// reproduced by seeding rng the same way.
+func GenerateRandomTree(fs absfs.FileSystem, root string, rng *rand.Rand, params TreeParams) (TreeSpec, error) {
+	spec := RandomTreeSpec(rng, params)
+	return spec, BuildTree(fs, root, spec)
+}
+
+// RandomTreeSpec returns the manifest of a random tree as made by
+// GenerateRandomTree without creating it.
+func RandomTreeSpec(rng *rand.Rand, params TreeParams) TreeSpec {
+	params = params.withDefaults()
+	spec := make(TreeSpec)
+	randomDir(spec, "", 0, rng, params)
+	return spec
+}
+
+func randomDir(spec TreeSpec, dir string, depth int, rng *rand.Rand, params TreeParams) {
+	chars := []rune(params.NameChars)
+	used := make(map[string]bool)
+	// Subdirectories may be empty, the root never is.
+	n := rng.Intn(params.MaxFanOut + 1)
+	if depth == 0 {
+		n = 1 + rng.Intn(params.MaxFanOut)
+	}
+	for i := 0; i < n; i++ {
+		var name string
+		for name == "" || name == "." || name == ".." || used[strings.ToLower(name)] {
+			runes := make([]rune, 1+rng.Intn(params.MaxNameLen))
+			for j := range runes {
+				runes[j] = chars[rng.Intn(len(chars))]
+			}
+			name = string(runes)
+		}
+		used[strings.ToLower(name)] = true
+		name = path.Join(dir, name)
+
+		if depth < params.MaxDepth && rng.Intn(3) == 0 {
+			spec[name] = TreeEntry{Dir: true}
+			randomDir(spec, name, depth+1, rng, params)
+			continue
+		}
+		data := make([]byte, rng.Intn(params.MaxFileSize+1))
+		rng.Read(data)
+		spec[name] = TreeEntry{Content: string(data)}
+	}
+}
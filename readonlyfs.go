@@ -0,0 +1,97 @@
+package fstesting
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// NewReadOnlyFS returns a FileSystem that passes reads, stats and directory
+// listings through to base and fails every modification with syscall.EROFS,
+// wrapped in an *os.PathError, or an *os.LinkError for Rename. It is both a
+// ready made read only wrapper and a reference for testing other read only
+// wrappers with WrapperSuite.
+func NewReadOnlyFS(base absfs.FileSystem) absfs.FileSystem {
+	return &readOnlyFS{base}
+}
+
+type readOnlyFS struct {
+	absfs.FileSystem
+}
+
+func (fs *readOnlyFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EROFS}
+	}
+	f, err := fs.FileSystem.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &readOnlyFile{f}, nil
+}
+
+func (fs *readOnlyFS) Open(name string) (absfs.File, error) {
+	return fs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (fs *readOnlyFS) Create(name string) (absfs.File, error) {
+	return fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *readOnlyFS) Mkdir(name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: name, Err: syscall.EROFS}
+}
+
+func (fs *readOnlyFS) MkdirAll(name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: name, Err: syscall.EROFS}
+}
+
+func (fs *readOnlyFS) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: syscall.EROFS}
+}
+
+func (fs *readOnlyFS) RemoveAll(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: syscall.EROFS}
+}
+
+func (fs *readOnlyFS) Rename(oldpath, newpath string) error {
+	return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EROFS}
+}
+
+func (fs *readOnlyFS) Chmod(name string, mode os.FileMode) error {
+	return &os.PathError{Op: "chmod", Path: name, Err: syscall.EROFS}
+}
+
+func (fs *readOnlyFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return &os.PathError{Op: "chtimes", Path: name, Err: syscall.EROFS}
+}
+
+func (fs *readOnlyFS) Chown(name string, uid, gid int) error {
+	return &os.PathError{Op: "chown", Path: name, Err: syscall.EROFS}
+}
+
+func (fs *readOnlyFS) Truncate(name string, size int64) error {
+	return &os.PathError{Op: "truncate", Path: name, Err: syscall.EROFS}
+}
+
+type readOnlyFile struct {
+	absfs.File
+}
+
+func (f *readOnlyFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.Name(), Err: syscall.EROFS}
+}
+
+func (f *readOnlyFile) WriteAt(p []byte, off int64) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.Name(), Err: syscall.EROFS}
+}
+
+func (f *readOnlyFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *readOnlyFile) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: f.Name(), Err: syscall.EROFS}
+}
@@ -0,0 +1,98 @@
+package fstesting
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReadOnlyFSWrapperSuite(t *testing.T) {
+	s := &WrapperSuite{
+		Wrapped:  NewReadOnlyFS(osFS{}),
+		Base:     osFS{},
+		TestDir:  t.TempDir(),
+		ReadOnly: true,
+	}
+	s.Run(t)
+}
+
+func TestReadOnlyFSMutators(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "file")
+	err := writeAll(osFS{}, name, []byte("read only"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ro := NewReadOnlyFS(osFS{})
+	now := time.Now()
+
+	for _, tt := range []struct {
+		op  string
+		err error
+	}{
+		{"OpenFile", openErr(ro.OpenFile(name, os.O_WRONLY, 0))},
+		{"Create", openErr(ro.Create(filepath.Join(dir, "new")))},
+		{"Mkdir", ro.Mkdir(filepath.Join(dir, "dir"), 0777)},
+		{"MkdirAll", ro.MkdirAll(filepath.Join(dir, "a", "b"), 0777)},
+		{"Remove", ro.Remove(name)},
+		{"RemoveAll", ro.RemoveAll(dir)},
+		{"Chmod", ro.Chmod(name, 0600)},
+		{"Chtimes", ro.Chtimes(name, now, now)},
+		{"Chown", ro.Chown(name, os.Getuid(), os.Getgid())},
+		{"Truncate", ro.Truncate(name, 0)},
+	} {
+		var perr *os.PathError
+		if !errors.As(tt.err, &perr) || !errors.Is(tt.err, syscall.EROFS) {
+			t.Errorf("%s error = %#v, want *os.PathError wrapping EROFS", tt.op, tt.err)
+		}
+	}
+
+	err = ro.Rename(name, filepath.Join(dir, "renamed"))
+	var lerr *os.LinkError
+	if !errors.As(err, &lerr) || !errors.Is(err, syscall.EROFS) {
+		t.Errorf("Rename error = %#v, want *os.LinkError wrapping EROFS", err)
+	}
+
+	f, err := ro.Open(name)
+	if err != nil {
+		t.Fatalf("Open(%q): %s", name, err)
+	}
+	defer f.Close()
+	for _, tt := range []struct {
+		op  string
+		err error
+	}{
+		{"Write", secondErr(f.Write([]byte("x")))},
+		{"WriteAt", secondErr(f.WriteAt([]byte("x"), 0))},
+		{"WriteString", secondErr(f.WriteString("x"))},
+		{"File.Truncate", f.Truncate(0)},
+	} {
+		var perr *os.PathError
+		if !errors.As(tt.err, &perr) || !errors.Is(tt.err, syscall.EROFS) {
+			t.Errorf("%s error = %#v, want *os.PathError wrapping EROFS", tt.op, tt.err)
+		}
+	}
+
+	// Nothing reached the base FileSystem.
+	got, err := os.ReadFile(name)
+	if err != nil || string(got) != "read only" {
+		t.Errorf("base file = %q, %v after mutations, want %q", got, err, "read only")
+	}
+}
+
+// openErr returns the error of an OpenFile call, closing the file if the call
+// unexpectedly succeeded.
+func openErr(f interface{ Close() error }, err error) error {
+	if err == nil {
+		f.Close()
+	}
+	return err
+}
+
+// secondErr returns the error of a call returning a count and an error.
+func secondErr(_ int, err error) error {
+	return err
+}
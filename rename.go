@@ -0,0 +1,115 @@
+package fstesting
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// testRename covers renaming files and directories to new and existing
+// paths.
+func (s *Suite) testRename(t *testing.T, testDir string) {
+	dir := path.Join(testDir, "rename")
+	s.mkdir(t, dir)
+
+	s.run(t, "Fresh", func(t *testing.T) {
+		oldpath, newpath := path.Join(dir, "fresh-old"), path.Join(dir, "fresh-new")
+		s.writeFile(t, oldpath, []byte("fresh"))
+
+		err := s.FS.Rename(oldpath, newpath)
+		if err != nil {
+			t.Fatalf("Rename(%q, %q): %s", oldpath, newpath, err)
+		}
+		s.checkContent(t, newpath, "fresh")
+		_, err = s.FS.Stat(oldpath)
+		if !os.IsNotExist(err) {
+			t.Errorf("Stat(%q) after Rename error = %v, want not exist", oldpath, err)
+		}
+	})
+
+	s.run(t, "OverFile", func(t *testing.T) {
+		oldpath, newpath := path.Join(dir, "over-old"), path.Join(dir, "over-new")
+		s.writeFile(t, oldpath, []byte("source"))
+		s.writeFile(t, newpath, []byte("replaced target"))
+
+		err := s.FS.Rename(oldpath, newpath)
+		if err != nil {
+			t.Fatalf("Rename(%q, %q): %s", oldpath, newpath, err)
+		}
+		s.checkContent(t, newpath, "source")
+	})
+
+	s.run(t, "DirOverNonEmptyDir", func(t *testing.T) {
+		oldpath, newpath := path.Join(dir, "dir-old"), path.Join(dir, "dir-new")
+		s.mkdir(t, oldpath)
+		s.populate(t, newpath, []string{"occupied"}, nil)
+
+		err := s.FS.Rename(oldpath, newpath)
+		if err == nil {
+			t.Fatalf("Rename(%q, %q) over a non-empty directory succeeded", oldpath, newpath)
+		}
+		// POSIX allows either ENOTEMPTY or EEXIST.
+		checkLinkError(t, "Rename", err, syscall.ENOTEMPTY, syscall.EEXIST)
+	})
+
+	s.run(t, "FileOverDir", func(t *testing.T) {
+		oldpath, newpath := path.Join(dir, "file-old"), path.Join(dir, "file-dir")
+		s.writeFile(t, oldpath, []byte("file"))
+		s.mkdir(t, newpath)
+
+		err := s.FS.Rename(oldpath, newpath)
+		if err == nil {
+			t.Fatalf("Rename(%q, %q) of a file over a directory succeeded", oldpath, newpath)
+		}
+		checkLinkError(t, "Rename", err, syscall.EISDIR, syscall.EEXIST)
+	})
+
+	s.run(t, "MissingSource", func(t *testing.T) {
+		oldpath, newpath := path.Join(dir, "missing-old"), path.Join(dir, "missing-new")
+
+		err := s.FS.Rename(oldpath, newpath)
+		if err == nil {
+			t.Fatalf("Rename(%q, %q) of a missing file succeeded", oldpath, newpath)
+		}
+		checkLinkError(t, "Rename", err, syscall.ENOENT)
+
+		var lerr *os.LinkError
+		if !errors.As(err, &lerr) {
+			return
+		}
+		if lerr.Op != "rename" {
+			t.Errorf("LinkError.Op = %q, want %q", lerr.Op, "rename")
+		}
+		if path.Base(lerr.Old) != path.Base(oldpath) || path.Base(lerr.New) != path.Base(newpath) {
+			t.Errorf("LinkError paths = %q -> %q, want %q -> %q", lerr.Old, lerr.New, oldpath, newpath)
+		}
+
+		// The reference error comes from the same rename in an empty host
+		// directory, as the paths of s.FS mean nothing to the host.
+		host := t.TempDir()
+		oserr := osFS{}.Rename(filepath.Join(host, "missing-old"), filepath.Join(host, "missing-new"))
+		if diff := CompareErrors(oserr, err); diff != nil {
+			t.Errorf("Rename error differs from os.Rename: %s", diff)
+		}
+	})
+}
+
+// checkLinkError fails the test unless err is an *os.LinkError wrapping one
+// of want.
+func checkLinkError(t *testing.T, op string, err error, want ...syscall.Errno) {
+	t.Helper()
+	var lerr *os.LinkError
+	if !errors.As(err, &lerr) {
+		t.Errorf("%s error = %#v, want *os.LinkError", op, err)
+		return
+	}
+	for _, errno := range want {
+		if errors.Is(err, errno) {
+			return
+		}
+	}
+	t.Errorf("%s error = %s, want one of %q", op, err, want)
+}
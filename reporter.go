@@ -0,0 +1,73 @@
+package fstesting
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+)
+
+// Reporter receives the outcome of each test a Suite runs, for example to
+// build a compatibility matrix of FileSystems and features. Names are
+// relative to the suite, such as "FileOperations/Seek".
+type Reporter interface {
+	OnPass(name string)
+	OnFail(name string, err error)
+	OnSkip(name, reason string)
+}
+
+// errFailed is passed to Reporter.OnFail. The testing package does not expose
+// failure messages, so the details are only in the test log.
+var errFailed = errors.New("test failed, see the test log for details")
+
+// run runs fn as the subtest name of t, unless it is excluded by the
+// patterns given to RunWithSkips or RunOnly, and reports its outcome to
+// s.Reporter by its name relative to the suite.
+func (s *Suite) run(t *testing.T, name string, fn func(t *testing.T)) bool {
+	return t.Run(name, func(t *testing.T) {
+		rel := strings.TrimPrefix(t.Name(), s.root+"/")
+		if s.Reporter != nil {
+			defer s.report(t, rel)
+		}
+		for _, pattern := range s.skipPatterns {
+			if ok, _ := path.Match(pattern, rel); ok {
+				s.skip(t, fmt.Sprintf("skipped by pattern %q", pattern))
+			}
+		}
+		if !s.selected(rel) {
+			s.skip(t, "not selected by RunOnly")
+		}
+		fn(t)
+	})
+}
+
+// skip skips t, remembering reason for the Reporter.
+func (s *Suite) skip(t *testing.T, reason string) {
+	t.Helper()
+	s.mu.Lock()
+	if s.skips == nil {
+		s.skips = make(map[string]string)
+	}
+	s.skips[t.Name()] = reason
+	s.mu.Unlock()
+	t.Skip(reason)
+}
+
+// report tells s.Reporter the outcome of the finished test t.
+func (s *Suite) report(t *testing.T, name string) {
+	switch {
+	case t.Failed():
+		s.Reporter.OnFail(name, errFailed)
+	case t.Skipped():
+		s.mu.Lock()
+		reason, ok := s.skips[t.Name()]
+		s.mu.Unlock()
+		if !ok {
+			reason = "skipped by test"
+		}
+		s.Reporter.OnSkip(name, reason)
+	default:
+		s.Reporter.OnPass(name)
+	}
+}
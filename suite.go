@@ -0,0 +1,351 @@
+package fstesting
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// Suite is a conformance test suite for absfs.FileSystem implementations.
+//
+//	func TestMyFS(t *testing.T) {
+//		s := &fstesting.Suite{FS: myfs.New(), Features: fstesting.DefaultFeatures()}
+//		s.Run(t)
+//	}
+type Suite struct {
+	// FS is the FileSystem under test.
+	FS absfs.FileSystem
+
+	// TestDir is the directory in FS where test directories are created. If
+	// empty FS.TempDir() is used.
+	TestDir string
+
+	// Features describes the capabilities of FS.
+	Features Features
+
+	// MaxNameLen and MaxPathLen are the longest file name and path the
+	// FileSystem is expected to accept. They default to 255 and 4096.
+	MaxNameLen int
+	MaxPathLen int
+
+	// Umask is the umask FS is expected to apply to the mode of new files
+	// and directories. If zero it is inferred from a file created with mode
+	// 0777.
+	Umask os.FileMode
+
+	// MaxBytes, if positive, is the capacity of a bounded FileSystem. The
+	// NoSpace tests fill it up and check that writes then fail with ENOSPC.
+	MaxBytes int64
+
+	// LargeDirCount is the number of entries created by the large directory
+	// test, which is skipped in short mode. It defaults to 10000.
+	LargeDirCount int
+
+	// SysType, if set, is the type FileInfo.Sys() must return for every
+	// file, such as reflect.TypeOf(&syscall.Stat_t{}) for the os package.
+	// Sys() returning nil or any other type is reported as a failure.
+	SysType reflect.Type
+
+	// SymlinkChainLength is the length of the chain of symlinks built to
+	// probe the limit on symlinks followed while resolving a name. It
+	// defaults to 50, above the limit of 40 on Linux.
+	SymlinkChainLength int
+
+	// Reopen, if set, returns a new instance of FS backed by the same storage.
+	// Tests that check durability use it to confirm that synced data
+	// survives reopening the filesystem.
+	Reopen func() (absfs.FileSystem, error)
+
+	// ConcurrencyLevel is the number of goroutines used by the concurrency
+	// tests. Zero disables them.
+	ConcurrencyLevel int
+
+	// Seed seeds the random number generators used by the stress and
+	// concurrency tests. If zero a seed is chosen from the current time. The
+	// seed in use is logged at the start of each run so that a failure can be
+	// replayed by setting Seed to the same value.
+	Seed int64
+
+	// GoldenDir, if set, is a host directory holding golden files of the
+	// results observed by the Golden group. Runs fail if the results drift
+	// from the golden files.
+	GoldenDir string
+
+	// UpdateGolden rewrites the golden files in GoldenDir instead of
+	// comparing against them. Callers typically set it from a flag of
+	// their own, such as -update.
+	UpdateGolden bool
+
+	// Reporter, if set, is told the outcome of each test group.
+	Reporter Reporter
+
+	seed         int64
+	root         string
+	skipPatterns []string
+	onlyPatterns []string
+	mu           sync.Mutex
+	skips        map[string]string
+}
+
+// Run runs every test in the suite as a subtest of t. Each run gets a fresh
+// directory under TestDir which is removed when the run completes.
+func (s *Suite) Run(t *testing.T) {
+	s.seed = s.Seed
+	if s.seed == 0 {
+		s.seed = time.Now().UnixNano()
+	}
+	s.root = t.Name()
+	t.Logf("fstesting seed %d", s.seed)
+	testDir := s.setup(t)
+
+	for _, g := range groups {
+		g := g
+		s.run(t, g.name, func(t *testing.T) {
+			if g.skip != nil {
+				reason := g.skip(s)
+				if reason != "" {
+					s.skip(t, reason)
+				}
+			}
+			g.run(s, t, testDir)
+		})
+	}
+}
+
+// RunWithSkips is like Run but skips every test whose name matches one of
+// the path.Match patterns in skips. Names are relative to the suite, for
+// example "FileOperations/Seek" or "ErrorSemantics/*".
+func (s *Suite) RunWithSkips(t *testing.T, skips ...string) {
+	checkPatterns(t, skips)
+	s.skipPatterns = skips
+	defer func() { s.skipPatterns = nil }()
+	s.Run(t)
+}
+
+// RunOnly is like Run but runs only the tests whose names match one of the
+// path.Match patterns in names, along with their descendants, skipping
+// everything else.
+func (s *Suite) RunOnly(t *testing.T, names ...string) {
+	checkPatterns(t, names)
+	s.onlyPatterns = names
+	defer func() { s.onlyPatterns = nil }()
+	s.Run(t)
+}
+
+// checkPatterns fails the test if any of patterns is malformed.
+func checkPatterns(t *testing.T, patterns []string) {
+	t.Helper()
+	for _, pattern := range patterns {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			t.Fatalf("bad pattern %q: %s", pattern, err)
+		}
+	}
+}
+
+// selected reports whether the test name should run under the patterns
+// given to RunOnly: a test runs if it, one of its ancestors or one of its
+// descendants matches a pattern.
+func (s *Suite) selected(name string) bool {
+	if len(s.onlyPatterns) == 0 {
+		return true
+	}
+	elems := strings.Split(name, "/")
+	for _, pattern := range s.onlyPatterns {
+		patterns := strings.Split(pattern, "/")
+		n := len(elems)
+		if len(patterns) < n {
+			n = len(patterns)
+		}
+		match := true
+		for i := 0; i < n && match; i++ {
+			match, _ = path.Match(patterns[i], elems[i])
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// group is a named top level group of tests in the Suite. If skip is set and
+// returns a non-empty reason the group is skipped.
+type group struct {
+	name string
+	skip func(s *Suite) string
+	run  func(s *Suite, t *testing.T, testDir string)
+}
+
+// groups is the registry of test groups, in the order Run runs them.
+var groups = []group{
+	{"FileOperations", nil, (*Suite).testFileOperations},
+	{"DirectoryOperations", nil, (*Suite).testDirectoryOperations},
+	{"Rename", nil, (*Suite).testRename},
+	{"ErrorSemantics", nil, (*Suite).testErrorSemantics},
+	{"PathHandling", nil, (*Suite).testPathHandling},
+	{"WorkingDirectory", nil, (*Suite).testWorkingDirectory},
+	{"NewFilerMethods", nil, (*Suite).testNewFilerMethods},
+	{"Symlinks", feature("Symlinks", func(f Features) bool { return f.Symlinks }), (*Suite).testSymlinks},
+	{"HardLinks", feature("HardLinks", func(f Features) bool { return f.HardLinks }), (*Suite).testHardLinks},
+	{"CaseSensitivity", nil, (*Suite).testCaseSensitivity},
+	{"Permissions", feature("Permissions", func(f Features) bool { return f.Permissions }), (*Suite).testPermissions},
+	{"Timestamps", feature("Timestamps", func(f Features) bool { return f.Timestamps }), (*Suite).testTimestamps},
+	{"CreationTime", feature("CreationTime", func(f Features) bool { return f.CreationTime }), (*Suite).testCreationTime},
+	{"CopyTree", nil, (*Suite).testCopyTree},
+	{"AtomicRename", feature("AtomicRename", func(f Features) bool { return f.AtomicRename }), (*Suite).testAtomicRename},
+	{"SparseFiles", feature("SparseFiles", func(f Features) bool { return f.SparseFiles }), (*Suite).testSparseFiles},
+	{"LargeFiles", feature("LargeFiles", func(f Features) bool { return f.LargeFiles }), (*Suite).testLargeFiles},
+	{"Ownership", feature("Ownership", func(f Features) bool { return f.Ownership }), (*Suite).testOwnership},
+	{"SysType", func(s *Suite) string {
+		if s.SysType == nil {
+			return "SysType is not set"
+		}
+		return ""
+	}, (*Suite).testSysType},
+	{"ExtendedAttrs", feature("ExtendedAttrs", func(f Features) bool { return f.ExtendedAttrs }), (*Suite).testXattrs},
+	{"Snapshots", feature("Snapshots", func(f Features) bool { return f.Snapshots }), (*Suite).testSnapshots},
+	{"Reflink", feature("Reflink", func(f Features) bool { return f.Reflink }), (*Suite).testReflink},
+	{"MemoryMapped", feature("MemoryMapped", func(f Features) bool { return f.MemoryMapped }), (*Suite).testMemoryMapped},
+	{"Locking", feature("Locking", func(f Features) bool { return f.Locking }), (*Suite).testLocking},
+	{"SpecialFiles", nil, (*Suite).testSpecialFiles},
+	{"SpaceReporting", feature("SpaceReporting", func(f Features) bool { return f.SpaceReporting }), (*Suite).testSpaceReporting},
+	{"Durability", feature("Durable", func(f Features) bool { return f.Durable }), (*Suite).testDurability},
+	{"NoSpace", func(s *Suite) string {
+		if s.MaxBytes <= 0 {
+			return "MaxBytes is 0"
+		}
+		return ""
+	}, (*Suite).testNoSpace},
+	{"Concurrency", func(s *Suite) string {
+		if s.ConcurrencyLevel <= 0 {
+			return "ConcurrencyLevel is 0"
+		}
+		return ""
+	}, (*Suite).testConcurrency},
+	{"Golden", nil, (*Suite).testGolden},
+}
+
+// feature returns a group skip function that skips unless has reports the
+// named feature as supported.
+func feature(name string, has func(f Features) bool) func(s *Suite) string {
+	return func(s *Suite) string {
+		if !has(s.Features) {
+			return name + " not supported"
+		}
+		return ""
+	}
+}
+
+// rand returns a random number generator derived from the run's seed. Each
+// goroutine should use its own generator, identified by stream.
+func (s *Suite) rand(stream int64) *rand.Rand {
+	return rand.New(rand.NewSource(s.seed + stream))
+}
+
+// setup creates the test directory for a run and registers its removal.
+func (s *Suite) setup(t *testing.T) string {
+	t.Helper()
+	return makeTestDir(t, s.FS, s.TestDir)
+}
+
+// testDirCount distinguishes test directories created in the same process.
+var testDirCount uint64
+
+// makeTestDir creates a uniquely named test directory with newTestDir and
+// removes it when tb completes.
+func makeTestDir(tb testing.TB, fs absfs.FileSystem, base string) string {
+	tb.Helper()
+	testDir, err := newTestDir(fs, base)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		err := fs.RemoveAll(testDir)
+		if err != nil {
+			tb.Errorf("RemoveAll(%q): %s", testDir, err)
+		}
+	})
+	return testDir
+}
+
+// newTestDir creates a uniquely named directory under base, or under
+// fs.TempDir() if base is empty. Names combine a timestamp, a per-process
+// counter and a random suffix, and a fresh name is tried if one is already
+// taken, so that concurrent runs from several test binaries don't share a
+// directory.
+func newTestDir(fs absfs.FileSystem, base string) (string, error) {
+	if base == "" {
+		base = fs.TempDir()
+	}
+	err := fs.MkdirAll(base, 0777)
+	if err != nil {
+		return "", fmt.Errorf("MkdirAll(%q): %s", base, err)
+	}
+
+	for i := 0; ; i++ {
+		n := atomic.AddUint64(&testDirCount, 1)
+		testDir := path.Join(base, fmt.Sprintf("fstesting%d-%d-%08x", time.Now().UnixNano(), n, rand.Uint32()))
+		err = fs.Mkdir(testDir, 0777)
+		if err == nil {
+			return testDir, nil
+		}
+		if !os.IsExist(err) || i == 10 {
+			return "", fmt.Errorf("Mkdir(%q): %s", testDir, err)
+		}
+	}
+}
+
+// writeFile creates name in s.FS with the given contents, failing the test on
+// any error.
+func (s *Suite) writeFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+	err := writeAll(s.FS, name, data)
+	if err != nil {
+		t.Fatalf("writing %q: %s", name, err)
+	}
+}
+
+// writeAll creates name in fs with the given contents.
+func writeAll(fs absfs.FileSystem, name string, data []byte) error {
+	f, err := fs.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// checkContent fails the test if the contents of name in s.FS are not want.
+func (s *Suite) checkContent(t *testing.T, name string, want string) {
+	t.Helper()
+	got, err := s.FS.ReadFile(name)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %s", name, err)
+	}
+	if string(got) != want {
+		t.Errorf("content of %q = %q, want %q", name, got, want)
+	}
+}
+
+// mkdir creates the directory name and any missing parents in s.FS, failing
+// the test on any error.
+func (s *Suite) mkdir(t *testing.T, name string) {
+	t.Helper()
+	err := s.FS.MkdirAll(name, 0777)
+	if err != nil {
+		t.Fatalf("MkdirAll(%q): %s", name, err)
+	}
+}
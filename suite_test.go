@@ -0,0 +1,141 @@
+package fstesting
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingReporter records the outcome of each test by name.
+type recordingReporter struct {
+	mu       sync.Mutex
+	outcomes map[string]string
+}
+
+func (r *recordingReporter) set(name, outcome string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.outcomes == nil {
+		r.outcomes = make(map[string]string)
+	}
+	r.outcomes[name] = outcome
+}
+
+func (r *recordingReporter) OnPass(name string)            { r.set(name, "pass") }
+func (r *recordingReporter) OnFail(name string, err error) { r.set(name, "fail") }
+func (r *recordingReporter) OnSkip(name, reason string)    { r.set(name, "skip: "+reason) }
+
+func (r *recordingReporter) check(t *testing.T, name, want string) {
+	t.Helper()
+	r.mu.Lock()
+	got := r.outcomes[name]
+	r.mu.Unlock()
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("%s outcome = %q, want %q", name, got, want)
+	}
+}
+
+func TestRunWithSkips(t *testing.T) {
+	r := new(recordingReporter)
+	s := &Suite{FS: osFS{}, TestDir: t.TempDir(), Features: OSFeatures(), Reporter: r}
+
+	// Skip every group but FileOperations, and Seek within it.
+	skips := []string{"FileOperations/Seek"}
+	for _, g := range groups {
+		if g.name != "FileOperations" {
+			skips = append(skips, g.name)
+		}
+	}
+	s.RunWithSkips(t, skips...)
+
+	r.check(t, "FileOperations/Seek", `skip: skipped by pattern "FileOperations/Seek"`)
+	r.check(t, "FileOperations/Name", "pass")
+	r.check(t, "FileOperations", "pass")
+	r.check(t, "DirectoryOperations", `skip: skipped by pattern "DirectoryOperations"`)
+}
+
+func TestRunOnly(t *testing.T) {
+	r := new(recordingReporter)
+	s := &Suite{FS: osFS{}, TestDir: t.TempDir(), Features: OSFeatures(), Reporter: r}
+	s.RunOnly(t, "FileOperations/Name")
+
+	r.check(t, "FileOperations/Name", "pass")
+	r.check(t, "FileOperations", "pass")
+	r.check(t, "FileOperations/Seek", "skip: not selected by RunOnly")
+	r.check(t, "DirectoryOperations", "skip: not selected by RunOnly")
+}
+
+func TestReportDirectSkip(t *testing.T) {
+	r := new(recordingReporter)
+	s := &Suite{Reporter: r, root: t.Name()}
+	s.run(t, "Direct", func(t *testing.T) { t.Skip("called t.Skip") })
+	s.run(t, "Routed", func(t *testing.T) { s.skip(t, "called s.skip") })
+
+	r.check(t, "Direct", "skip: skipped by test")
+	r.check(t, "Routed", "skip: called s.skip")
+}
+
+func TestSelected(t *testing.T) {
+	for _, tt := range []struct {
+		patterns []string
+		name     string
+		want     bool
+	}{
+		{nil, "FileOperations/Seek", true},
+		{[]string{"FileOperations/Seek"}, "FileOperations", true},
+		{[]string{"FileOperations/Seek"}, "FileOperations/Seek", true},
+		{[]string{"FileOperations/Seek"}, "FileOperations/Seek/Sub", true},
+		{[]string{"FileOperations/Seek"}, "FileOperations/Name", false},
+		{[]string{"FileOperations/Seek"}, "DirectoryOperations", false},
+		{[]string{"FileOperations/Seek"}, "FileOperationsX/Seek", false},
+		{[]string{"*/Seek"}, "DirectoryOperations", true},
+		{[]string{"*/Seek"}, "DirectoryOperations/Seek", true},
+		{[]string{"*/Seek"}, "FileOperations/Name", false},
+		{[]string{"Perm*"}, "Permissions/ModeBits", true},
+		{[]string{"Perm*"}, "PathHandling", false},
+		{[]string{"Rename", "Symlinks/Loop"}, "Symlinks/Loop", true},
+		{[]string{"Rename", "Symlinks/Loop"}, "Rename/Fresh", true},
+		{[]string{"Rename", "Symlinks/Loop"}, "Symlinks/NoFollow", false},
+	} {
+		s := &Suite{onlyPatterns: tt.patterns}
+		if got := s.selected(tt.name); got != tt.want {
+			t.Errorf("selected(%q) with patterns %q = %t, want %t", tt.name, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestMakeTestDirConcurrent(t *testing.T) {
+	const n = 50
+	base := t.TempDir()
+	names := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			names[i], errs[i] = newTestDir(osFS{}, base)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			t.Errorf("makeTestDir returned %q twice", name)
+		}
+		seen[name] = true
+	}
+	entries, err := osFS{}.ReadDir(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != n {
+		t.Errorf("%d directories created, want %d", len(entries), n)
+	}
+}
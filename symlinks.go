@@ -0,0 +1,252 @@
+package fstesting
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// testSymlinks covers how symlinks are created, read and followed.
+func (s *Suite) testSymlinks(t *testing.T, testDir string) {
+	sl, ok := s.FS.(absfs.SymLinker)
+	if !ok {
+		s.skip(t, fmt.Sprintf("%T does not implement absfs.SymLinker", s.FS))
+	}
+	dir := path.Join(testDir, "symlinks")
+	s.mkdir(t, dir)
+
+	s.run(t, "NoFollow", func(t *testing.T) {
+		if oNofollow == 0 {
+			s.skip(t, "O_NOFOLLOW is not defined on this platform")
+		}
+		target := path.Join(dir, "nofollow-target")
+		link := path.Join(dir, "nofollow-link")
+		s.writeFile(t, target, []byte("target"))
+		err := sl.Symlink(target, link)
+		if err != nil {
+			t.Fatalf("Symlink(%q, %q): %s", target, link, err)
+		}
+
+		f, err := s.FS.OpenFile(target, os.O_RDONLY|oNofollow, 0)
+		if errors.Is(err, absfs.ErrNotImplemented) || errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EINVAL) {
+			s.skip(t, fmt.Sprintf("O_NOFOLLOW not supported: %s", err))
+		}
+		if err != nil {
+			t.Fatalf("OpenFile(%q, O_NOFOLLOW) of a regular file: %s", target, err)
+		}
+		f.Close()
+
+		// FreeBSD reports EMLINK where other systems report ELOOP.
+		f, err = s.FS.OpenFile(link, os.O_RDONLY|oNofollow, 0)
+		if err == nil {
+			f.Close()
+			t.Fatalf("OpenFile(%q, O_NOFOLLOW) followed the symlink", link)
+		}
+		checkPathError(t, "OpenFile", err, syscall.ELOOP, syscall.EMLINK)
+	})
+	s.run(t, "TargetVerbatim", func(t *testing.T) {
+		// Targets are stored as given, they need not exist and are only
+		// resolved when the link is followed.
+		targets := []string{
+			"./foo//bar",
+			"foo/./bar/",
+			"../foo/../bar",
+			"//foo///bar",
+			".",
+			"..",
+			"foo/",
+		}
+		for i, target := range targets {
+			link := path.Join(dir, fmt.Sprintf("verbatim%d", i))
+			err := sl.Symlink(target, link)
+			if err != nil {
+				t.Errorf("Symlink(%q, %q): %s", target, link, err)
+				continue
+			}
+			got, err := sl.Readlink(link)
+			if err != nil {
+				t.Errorf("Readlink(%q): %s", link, err)
+			} else if got != target {
+				t.Errorf("Readlink(%q) = %q, want %q unchanged", link, got, target)
+			}
+		}
+	})
+	s.run(t, "LinkMetadata", func(t *testing.T) {
+		target := path.Join(dir, "meta-target")
+		link := path.Join(dir, "meta-link")
+		s.writeFile(t, target, []byte("target"))
+		err := sl.Symlink(target, link)
+		if err != nil {
+			t.Fatalf("Symlink(%q, %q): %s", target, link, err)
+		}
+
+		info, err := s.FS.Stat(link)
+		if err != nil {
+			t.Fatalf("Stat(%q): %s", link, err)
+		}
+		if !info.Mode().IsRegular() || info.Size() != int64(len("target")) {
+			t.Errorf("Stat(%q) = mode %s size %d, want the target's", link, info.Mode(), info.Size())
+		}
+		s.checkLink(t, sl, link)
+
+		if s.Features.Permissions {
+			err = s.FS.Chmod(link, 0600)
+			if err != nil {
+				t.Fatalf("Chmod(%q, 0600): %s", link, err)
+			}
+			s.checkMode(t, target, 0600)
+			s.checkLink(t, sl, link)
+		}
+
+		if lfs, ok := s.FS.(interface {
+			Lchmod(name string, mode os.FileMode) error
+		}); ok {
+			// FileSystems without link modes, such as Linux, report
+			// ENOTSUP; any other success must change the link itself.
+			err = lfs.Lchmod(link, 0644)
+			switch {
+			case errors.Is(err, absfs.ErrNotImplemented) || errors.Is(err, syscall.ENOTSUP):
+			case err != nil:
+				t.Errorf("Lchmod(%q, 0644): %s", link, err)
+			default:
+				info, err := sl.Lstat(link)
+				if err != nil {
+					t.Fatalf("Lstat(%q): %s", link, err)
+				}
+				if info.Mode().Perm() != 0644 {
+					t.Errorf("Lstat(%q) after Lchmod(0644) has mode %s", link, info.Mode())
+				}
+			}
+			if s.Features.Permissions {
+				s.checkMode(t, target, 0600)
+			}
+		}
+
+		uid, gid := os.Getuid(), os.Getgid()
+		if uid >= 0 {
+			err = sl.Lchown(link, uid, gid)
+			if err != nil && !errors.Is(err, absfs.ErrNotImplemented) && !errors.Is(err, os.ErrPermission) {
+				t.Errorf("Lchown(%q, %d, %d): %s", link, uid, gid, err)
+			}
+			s.checkLink(t, sl, link)
+		}
+
+		if lfs, ok := s.FS.(interface {
+			Lchtimes(name string, atime, mtime time.Time) error
+		}); ok {
+			before, err := s.FS.Stat(target)
+			if err != nil {
+				t.Fatalf("Stat(%q): %s", target, err)
+			}
+			mtime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+			err = lfs.Lchtimes(link, mtime, mtime)
+			if errors.Is(err, absfs.ErrNotImplemented) || errors.Is(err, syscall.ENOTSUP) {
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lchtimes(%q): %s", link, err)
+			}
+			linfo := s.checkLink(t, sl, link)
+			if linfo != nil && !linfo.ModTime().Equal(mtime) {
+				t.Errorf("Lstat(%q).ModTime() = %s after Lchtimes, want %s", link, linfo.ModTime(), mtime)
+			}
+			after, err := s.FS.Stat(target)
+			if err != nil {
+				t.Fatalf("Stat(%q): %s", target, err)
+			}
+			if !after.ModTime().Equal(before.ModTime()) {
+				t.Errorf("Lchtimes(%q) changed the target's ModTime from %s to %s", link, before.ModTime(), after.ModTime())
+			}
+		}
+	})
+	s.run(t, "Loop", func(t *testing.T) {
+		a, b := path.Join(dir, "loop-a"), path.Join(dir, "loop-b")
+		for _, link := range [][2]string{{a, b}, {b, a}} {
+			err := sl.Symlink(link[0], link[1])
+			if err != nil {
+				t.Fatalf("Symlink(%q, %q): %s", link[0], link[1], err)
+			}
+		}
+		err := s.boundedStat(t, a)
+		if err == nil {
+			t.Fatalf("Stat(%q) of a symlink loop succeeded", a)
+		}
+		checkPathError(t, "Stat", err, syscall.ELOOP)
+	})
+	s.run(t, "ChainDepth", func(t *testing.T) {
+		n := s.SymlinkChainLength
+		if n <= 0 {
+			n = 50
+		}
+		target := path.Join(dir, "chain-target")
+		s.writeFile(t, target, []byte("target"))
+
+		// link i points at link i-1, so resolving link i follows i+1
+		// symlinks. Once the limit is reached every longer chain must
+		// fail too.
+		limit := -1
+		prev := target
+		for i := 0; i < n; i++ {
+			link := path.Join(dir, fmt.Sprintf("chain%03d", i))
+			err := sl.Symlink(prev, link)
+			if err != nil {
+				t.Fatalf("Symlink(%q, %q): %s", prev, link, err)
+			}
+			prev = link
+
+			err = s.boundedStat(t, link)
+			switch {
+			case err == nil && limit >= 0:
+				t.Errorf("Stat of a chain of %d symlinks succeeded after a chain of %d failed", i+1, limit+1)
+			case err != nil && limit < 0:
+				checkPathError(t, "Stat", err, syscall.ELOOP)
+				limit = i
+			}
+		}
+		if limit >= 0 {
+			t.Logf("symlinks followed while resolving a name are limited to %d", limit)
+		} else {
+			t.Logf("resolved a chain of %d symlinks", n)
+		}
+	})
+}
+
+// checkLink fails the test unless Lstat reports name is a symlink, and
+// returns the FileInfo of the link.
+func (s *Suite) checkLink(t *testing.T, sl absfs.SymLinker, name string) os.FileInfo {
+	t.Helper()
+	info, err := sl.Lstat(name)
+	if err != nil {
+		t.Errorf("Lstat(%q): %s", name, err)
+		return nil
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Lstat(%q).Mode() = %s, want a symlink", name, info.Mode())
+	}
+	return info
+}
+
+// boundedStat calls Stat(name) and fails the test if it does not return
+// within 5 seconds, as happens if an implementation follows symlinks without
+// a limit.
+func (s *Suite) boundedStat(t *testing.T, name string) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.FS.Stat(name)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Stat(%q) did not return within 5s", name)
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+package fstesting
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testSysType checks that every way of getting a FileInfo returns a Sys()
+// value of type s.SysType, so that code type asserting on it cannot panic.
+func (s *Suite) testSysType(t *testing.T, testDir string) {
+	dir := path.Join(testDir, "sys")
+	name := path.Join(dir, "file")
+	s.mkdir(t, dir)
+	s.writeFile(t, name, []byte("sys"))
+
+	check := func(what string, info os.FileInfo) {
+		t.Helper()
+		sys := info.Sys()
+		if sys == nil {
+			t.Errorf("%s: Sys() returned nil, want %s", what, s.SysType)
+		} else if reflect.TypeOf(sys) != s.SysType {
+			t.Errorf("%s: Sys() returned %T, want %s", what, sys, s.SysType)
+		}
+	}
+
+	for _, n := range []string{name, dir} {
+		info, err := s.FS.Stat(n)
+		if err != nil {
+			t.Fatalf("Stat(%q): %s", n, err)
+		}
+		check(fmt.Sprintf("Stat(%q)", n), info)
+
+		f, err := s.FS.Open(n)
+		if err != nil {
+			t.Fatalf("Open(%q): %s", n, err)
+		}
+		info, err = f.Stat()
+		f.Close()
+		if err != nil {
+			t.Fatalf("File.Stat of %q: %s", n, err)
+		}
+		check(fmt.Sprintf("File.Stat of %q", n), info)
+	}
+
+	entries, err := s.FS.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir(%q): %s", dir, err)
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			t.Fatalf("DirEntry.Info() of %q: %s", e.Name(), err)
+		}
+		check(fmt.Sprintf("DirEntry.Info() of %q", e.Name()), info)
+	}
+
+	f, err := s.FS.Open(dir)
+	if err != nil {
+		t.Fatalf("Open(%q): %s", dir, err)
+	}
+	defer f.Close()
+	infos, err := f.Readdir(-1)
+	if err != nil {
+		t.Fatalf("File.Readdir(-1): %s", err)
+	}
+	for _, info := range infos {
+		check(fmt.Sprintf("File.Readdir entry %q", info.Name()), info)
+	}
+}
+
+// sysInt returns the first integer field of info.Sys() found in names. The
+// field names of syscall.Stat_t differ between platforms, and custom
+// filesystems are free to use their own types, so the lookup is done by
+// reflection. The boolean result is false if no such field exists.
+func sysInt(info os.FileInfo, names ...string) (int64, bool) {
+	v, ok := sysStruct(info)
+	if !ok {
+		return 0, false
+	}
+
+	for _, name := range names {
+		if i, ok := intValue(v.FieldByName(name)); ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// sysTime is like sysInt for time fields. It understands time.Time, the
+// Timespec structs of Unix systems and the Filetime struct of Windows.
+func sysTime(info os.FileInfo, names ...string) (time.Time, bool) {
+	v, ok := sysStruct(info)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	for _, name := range names {
+		f := v.FieldByName(name)
+		if f.Kind() != reflect.Struct || !f.CanInterface() {
+			continue
+		}
+		if t, ok := f.Interface().(time.Time); ok {
+			return t, true
+		}
+		sec, ok1 := intValue(f.FieldByName("Sec"))
+		nsec, ok2 := intValue(f.FieldByName("Nsec"))
+		if ok1 && ok2 {
+			return time.Unix(sec, nsec), true
+		}
+		low, ok1 := intValue(f.FieldByName("LowDateTime"))
+		high, ok2 := intValue(f.FieldByName("HighDateTime"))
+		if ok1 && ok2 {
+			// 100-nanosecond intervals since January 1, 1601.
+			const epochDelta = 116444736000000000
+			return time.Unix(0, (high<<32+low-epochDelta)*100), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// accessTime returns the access time of info from an AccessTime method on
+// info or info.Sys(), or from the fields of info.Sys().
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	for _, v := range []interface{}{info, info.Sys()} {
+		if a, ok := v.(interface{ AccessTime() time.Time }); ok {
+			return a.AccessTime(), true
+		}
+	}
+	return sysTime(info, "Atim", "Atimespec", "LastAccessTime", "Atime")
+}
+
+// birthTime returns the creation time of info from a BirthTime method on info
+// or info.Sys(), or from the fields of info.Sys().
+func birthTime(info os.FileInfo) (time.Time, bool) {
+	for _, v := range []interface{}{info, info.Sys()} {
+		if b, ok := v.(interface{ BirthTime() time.Time }); ok {
+			return b.BirthTime(), true
+		}
+	}
+	return sysTime(info, "Birthtimespec", "Birthtim", "CreationTime", "Btime")
+}
+
+// sysStruct returns the struct info.Sys() holds or points to.
+func sysStruct(info os.FileInfo) (reflect.Value, bool) {
+	v := reflect.ValueOf(info.Sys())
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.Kind() == reflect.Struct
+}
+
+// intValue returns the value of v if it is an integer.
+func intValue(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), true
+	}
+	return 0, false
+}
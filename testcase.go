@@ -7,10 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -138,22 +140,30 @@ func init() {
 	errorerrorstring := errors.New("error")
 	errorstring := new(ErrorString)
 	gob.Register(patherr)
+	gob.Register(new(os.LinkError))
 	gob.Register(errno)
 	gob.Register(errorerrorstring) // apparently this won't work
 	gob.Register(errorstring)
 
 }
 
+// timestampName returns prefix followed by a timestamp, the process ID and a
+// counter. Unlike RFC 3339 the timestamp has no colons, which are invalid in
+// Windows file names, and names made within the same second don't collide.
+func timestampName(prefix string) string {
+	n := atomic.AddUint64(&testDirCount, 1)
+	timestamp := time.Now().UTC().Format("20060102T150405.000000000Z")
+	return fmt.Sprintf("%s%s-%d-%d", prefix, timestamp, os.Getpid(), n)
+}
+
 func testDir() (testdir string, cleanup func(), err error) {
 
 	// assign noop to cleanup until there is something to clean up.
 	cleanup = func() {}
-	timestamp := time.Now().Format(time.RFC3339)
-	testdir = filepath.Join(os.TempDir(), fmt.Sprintf("fstesting%s", timestamp))
+	testdir = filepath.Join(os.TempDir(), timestampName("fstesting"))
 
 	err = os.Mkdir(testdir, 0777)
 	if err != nil {
-		panic(err.Error())
 		return testdir, cleanup, err
 	}
 
@@ -183,12 +193,12 @@ func testDir() (testdir string, cleanup func(), err error) {
 // and removes testdir and all of it's contents.
 func FsTestDir(fs absfs.FileSystem, path string) (testdir string, cleanup func(), err error) {
 
-	timestamp := time.Now().Format(time.RFC3339)
-	testdir = filepath.Join(path, fmt.Sprintf("FsTestDir%s", timestamp))
+	// assign noop to cleanup until there is something to clean up.
+	cleanup = func() {}
+	testdir = filepath.Join(path, timestampName("FsTestDir"))
 	var cwd string
 	cwd, err = fs.Getwd()
 	if err != nil {
-		panic(err)
 		return testdir, cleanup, err
 	}
 	cleanup = func() {
@@ -200,10 +210,9 @@ func FsTestDir(fs absfs.FileSystem, path string) (testdir string, cleanup func()
 	}
 
 	for _, path := range []string{path, testdir} {
-		fmt.Printf("Mkdir(%q)\n", path)
 		err := fs.Mkdir(path, 0777)
-		if os.IsExist(err) {
-			continue
+		if err != nil && !os.IsExist(err) {
+			return testdir, cleanup, err
 		}
 	}
 
@@ -215,13 +224,14 @@ func FsTestDir(fs absfs.FileSystem, path string) (testdir string, cleanup func()
 	return testdir, cleanup, nil
 }
 
-// GenerateTestcases runs all tests on the `os` package to establish baseline
+// AutoTest runs all tests on the `os` package to establish baseline
 // results that can be used to test that `absfs` FileSystems are consistent with
 // native file system support.
-// If not `nil` GenerateTestcases will call `fn` with each generated testcase. If
-// `fn` returns an error then testcase generation will stop and GenerateTestcases
+// OpenFile testcases are generated for every flag and permission combination,
+// followed by testcases for each of the Operations.
+// If not `nil` AutoTest will call `fn` with each generated testcase. If
+// `fn` returns an error then testcase generation will stop and AutoTest
 // will return an the same error and the testcases crated so far.
-// (TODO: many tests still to be added to exercise the entire FileSystem interface)
 func AutoTest(startno int, fn func(*Testcase) error) error {
 	testdir, cleanup, err := testDir()
 	defer cleanup()
@@ -247,7 +257,7 @@ func AutoTest(startno int, fn func(*Testcase) error) error {
 			return nil
 		}
 	}
-	return ForEveryFlag(func(flag int) error {
+	err = ForEveryFlag(func(flag int) error {
 		return ForEveryPermission(func(mode os.FileMode) error {
 			for _, pathPrefix := range []string{testdir, ".", ""} {
 				for _, condition := range preconditions {
@@ -350,6 +360,74 @@ func AutoTest(startno int, fn func(*Testcase) error) error {
 			return nil
 		})
 	})
+	if err != nil {
+		return err
+	}
+
+	for _, op := range Operations {
+		for _, pathPrefix := range []string{testdir, ".", ""} {
+			for _, condition := range preconditions {
+				if testNo < startno {
+					testNo++
+					continue
+				}
+
+				testcase := &Testcase{
+					TestNo:       testNo,
+					PreCondition: condition,
+					Op:           op.Op,
+				}
+				name, err := pretest(osFS{}, pathPrefix, testcase)
+				if err != nil {
+					return err
+				}
+				testcase.Path = name
+				testcase.Errors = make(map[string]*ErrorReport)
+				op.Run(osFS{}, name, testcase.Errors)
+
+				err = fn(testcase)
+				if err != nil {
+					return err
+				}
+
+				testNo++
+			}
+		}
+	}
+	return nil
+}
+
+// An Operation generates testcases for a single FileSystem method. Run is
+// called with the name of a file prepared according to the testcase
+// precondition and records an ErrorReport for each call it makes.
+type Operation struct {
+	Op  string
+	Run func(fs absfs.FileSystem, name string, errs map[string]*ErrorReport)
+}
+
+// Operations are the operations, other than "openfile", that AutoTest
+// generates testcases for and FsTest knows how to run.
+var Operations = []Operation{
+	{"mkdir", func(fs absfs.FileSystem, name string, errs map[string]*ErrorReport) {
+		err := fs.Mkdir(name, 0777)
+		errs["Mkdir"] = NewErrorReport("Mkdir", name, err, fmt.Sprintf("%+v", err))
+	}},
+	{"remove", func(fs absfs.FileSystem, name string, errs map[string]*ErrorReport) {
+		err := fs.Remove(name)
+		errs["Remove"] = NewErrorReport("Remove", name, err, fmt.Sprintf("%+v", err))
+	}},
+	{"rename", func(fs absfs.FileSystem, name string, errs map[string]*ErrorReport) {
+		err := fs.Rename(name, name+".renamed")
+		errs["Rename"] = NewErrorReport("Rename", name, err, fmt.Sprintf("%+v", err))
+	}},
+	{"stat", func(fs absfs.FileSystem, name string, errs map[string]*ErrorReport) {
+		_, err := fs.Stat(name)
+		errs["Stat"] = NewErrorReport("Stat", name, err, fmt.Sprintf("%+v", err))
+	}},
+	{"truncate", func(fs absfs.FileSystem, name string, errs map[string]*ErrorReport) {
+		err := fs.Truncate(name, 5)
+		errs["Truncate"] = NewErrorReport("Truncate", name, err, fmt.Sprintf("%+v", err))
+	}},
 }
 
 func FsTest(fs absfs.FileSystem, path string, testcase *Testcase) (*Testcase, error) {
@@ -359,6 +437,22 @@ func FsTest(fs absfs.FileSystem, path string, testcase *Testcase) (*Testcase, er
 		return nil, err
 	}
 
+	for _, op := range Operations {
+		if op.Op != testcase.Op {
+			continue
+		}
+		newtestcase := &Testcase{
+			TestNo:       testcase.TestNo,
+			PreCondition: testcase.PreCondition,
+			Op:           op.Op,
+			Path:         name,
+			Errors:       make(map[string]*ErrorReport),
+		}
+		op.Run(fs, name, newtestcase.Errors)
+		posttest(fs, newtestcase)
+		return newtestcase, nil
+	}
+
 	newtestcase, err := test(fs, testcase.TestNo, name, testcase.Flags, testcase.Mode, testcase.PreCondition)
 	posttest(fs, newtestcase)
 	return newtestcase, err
@@ -465,22 +559,62 @@ func test(fs absfs.FileSystem, testNo int, name string, flags int, mode os.FileM
 	return testcase, nil
 }
 
+// CompareErrors returns nil if err1 and err2 describe the same failure, or an
+// error describing how they differ. Wrapped errors are unwrapped, so an
+// *os.PathError returned through fmt.Errorf("%w") compares equal to the bare
+// *os.PathError. Path errors are compared by operation, base name and
+// underlying syscall.Errno, and all errors must agree on whether they match
+// fs.ErrNotExist, fs.ErrExist and fs.ErrPermission.
 func CompareErrors(err1 error, err2 error) error {
+	if err1 == nil || err2 == nil {
+		if err1 == nil && err2 == nil {
+			return nil
+		}
+		return fmt.Errorf("err1 is %v err2 is %v", err1, err2)
+	}
+
+	for _, target := range []error{fs.ErrNotExist, fs.ErrExist, fs.ErrPermission} {
+		is1, is2 := errors.Is(err1, target), errors.Is(err2, target)
+		if is1 != is2 {
+			return fmt.Errorf("errors differ on %q: %t != %t (%q, %q)", target, is1, is2, err1, err2)
+		}
+	}
 
-	switch v1 := err1.(type) {
+	var l1, l2 *os.LinkError
+	ok1, ok2 := errors.As(err1, &l1), errors.As(err2, &l2)
+	if ok1 != ok2 {
+		return fmt.Errorf("errors differ in type %T != %T", err1, err2)
+	}
 
-	case nil:
-		if err2 == nil {
-			return nil
+	if ok1 {
+		var list []string
+
+		if path.Base(l1.Old) != path.Base(l2.Old) || path.Base(l1.New) != path.Base(l2.New) {
+			list = append(list, fmt.Sprintf("paths not equal %q -> %q != %q -> %q", l1.Old, l1.New, l2.Old, l2.New))
+		}
+
+		if l1.Op != l2.Op {
+			list = append(list, fmt.Sprintf("ops not equal %q != %q", l1.Op, l2.Op))
 		}
-		return fmt.Errorf("err1 is <nil> err2 is %s", err2)
 
-	case *os.PathError:
-		v2, ok := err2.(*os.PathError)
-		if !ok {
-			return fmt.Errorf("errors differ in type %T != %T", err1, err2)
+		if diff := compareErrno(l1.Err, l2.Err); diff != "" {
+			list = append(list, diff)
+		}
+
+		if len(list) == 0 {
+			return nil
 		}
 
+		return fmt.Errorf("os.LinkErrors:  %s", strings.Join(list, "; "))
+	}
+
+	var v1, v2 *os.PathError
+	ok1, ok2 = errors.As(err1, &v1), errors.As(err2, &v2)
+	if ok1 != ok2 {
+		return fmt.Errorf("errors differ in type %T != %T", err1, err2)
+	}
+
+	if ok1 {
 		var list []string
 
 		if path.Base(v1.Path) != path.Base(v2.Path) {
@@ -491,8 +625,8 @@ func CompareErrors(err1 error, err2 error) error {
 			list = append(list, fmt.Sprintf("ops not equal %q != %q", v1.Op, v2.Op))
 		}
 
-		if v1.Err.Error() != v2.Err.Error() {
-			list = append(list, fmt.Sprintf("errors not equal %q != %q", v1.Err.Error(), v2.Err.Error()))
+		if diff := compareErrno(v1.Err, v2.Err); diff != "" {
+			list = append(list, diff)
 		}
 
 		if len(list) == 0 {
@@ -500,21 +634,28 @@ func CompareErrors(err1 error, err2 error) error {
 		}
 
 		return fmt.Errorf("os.PathErrors:  %s", strings.Join(list, "; "))
-
-	case *ErrorString:
-		v2, ok := err2.(*ErrorString)
-		if !ok {
-			return fmt.Errorf("errors differ in type %T != %T", err1, err2)
-		}
-		_ = v2
-
-	default:
-		panic(fmt.Sprintf("un-handled types %T, %T", err1, err2))
 	}
 
 	if err1.Error() != err2.Error() {
 		return fmt.Errorf("unknown unequal errors %T & %T differ %q != %q", err1, err2, err1, err2)
 	}
 
-	return nil // fmt.Errorf("unknown matching errors %T, %q", err1, err2)
+	return nil
+}
+
+// compareErrno describes the difference between the underlying errors of two
+// PathErrors or LinkErrors, or returns "" if they match.
+func compareErrno(err1, err2 error) string {
+	var errno1, errno2 syscall.Errno
+	if errors.As(err1, &errno1) && errors.As(err2, &errno2) {
+		if errno1 != errno2 {
+			return fmt.Sprintf("errnos not equal %q != %q", errno1, errno2)
+		}
+		return ""
+	}
+
+	if fmt.Sprint(err1) != fmt.Sprint(err2) {
+		return fmt.Sprintf("errors not equal %q != %q", err1, err2)
+	}
+	return ""
 }
@@ -0,0 +1,189 @@
+package fstesting
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/absfs/absfs"
+)
+
+func TestCompareErrors(t *testing.T) {
+	enoent := &os.PathError{Op: "open", Path: "/a/missing", Err: syscall.ENOENT}
+	eexist := &os.PathError{Op: "mkdir", Path: "/a/dir", Err: syscall.EEXIST}
+	link := &os.LinkError{Op: "rename", Old: "/a/old", New: "/a/new", Err: syscall.ENOENT}
+
+	for _, tt := range []struct {
+		name       string
+		err1, err2 error
+		equal      bool
+	}{
+		{"both nil", nil, nil, true},
+		{"one nil", nil, enoent, false},
+		{"same PathError", enoent, enoent, true},
+		{"PathError in other directory", enoent, &os.PathError{Op: "open", Path: "/b/missing", Err: syscall.ENOENT}, true},
+		{"wrapped PathError", enoent, fmt.Errorf("opening: %w", enoent), true},
+		{"PathError op differs", enoent, &os.PathError{Op: "stat", Path: "/a/missing", Err: syscall.ENOENT}, false},
+		{"PathError name differs", enoent, &os.PathError{Op: "open", Path: "/a/other", Err: syscall.ENOENT}, false},
+		{"PathError errno differs", eexist, &os.PathError{Op: "mkdir", Path: "/a/dir", Err: syscall.ENOTDIR}, false},
+		{"PathError errno and sentinel", enoent, &os.PathError{Op: "open", Path: "/a/missing", Err: fs.ErrNotExist}, false},
+		{"PathError and LinkError", enoent, link, false},
+		{"same LinkError", link, link, true},
+		{"wrapped LinkError", link, fmt.Errorf("renaming: %w", link), true},
+		{"LinkError new differs", link, &os.LinkError{Op: "rename", Old: "/a/old", New: "/a/other", Err: syscall.ENOENT}, false},
+		{"LinkError errno differs", link, &os.LinkError{Op: "rename", Old: "/a/old", New: "/a/new", Err: syscall.EXDEV}, false},
+		{"same sentinel", fs.ErrNotExist, fs.ErrNotExist, true},
+		{"different sentinels", fs.ErrNotExist, fs.ErrExist, false},
+		{"sentinel and PathError", fs.ErrNotExist, enoent, false},
+		{"errors.New with same text", errors.New("boom"), errors.New("boom"), true},
+		{"errors.New with other text", errors.New("boom"), errors.New("bang"), false},
+	} {
+		err := CompareErrors(tt.err1, tt.err2)
+		if (err == nil) != tt.equal {
+			t.Errorf("%s: CompareErrors(%v, %v) = %v, want equal %t", tt.name, tt.err1, tt.err2, err, tt.equal)
+		}
+	}
+}
+
+func TestTimestampNameUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		name := timestampName("fstesting")
+		if seen[name] {
+			t.Fatalf("timestampName returned %q twice", name)
+		}
+		seen[name] = true
+		if strings.ContainsAny(name, `:\/`) {
+			t.Fatalf("timestampName returned %q, which is not a valid file name on every platform", name)
+		}
+	}
+}
+
+func TestFsTestFileSystems(t *testing.T) {
+	base, readonly := t.TempDir(), t.TempDir()
+	for _, tt := range []struct {
+		op    string
+		match bool
+	}{
+		{"stat", true},   // both report ENOENT
+		{"mkdir", false}, // osFS creates the directory, readOnlyFS returns EROFS
+	} {
+		testcase := &Testcase{TestNo: 1, PreCondition: "notcreated", Op: tt.op}
+		want, err := FsTest(osFS{}, base, testcase)
+		if err != nil {
+			t.Fatalf("FsTest(osFS, %s): %s", tt.op, err)
+		}
+		got, err := FsTest(NewReadOnlyFS(osFS{}), readonly, testcase)
+		if err != nil {
+			t.Fatalf("FsTest(readOnlyFS, %s): %s", tt.op, err)
+		}
+		if len(want.Errors) != 1 || len(got.Errors) != 1 {
+			t.Fatalf("%s: got %d and %d error reports, want 1 each", tt.op, len(want.Errors), len(got.Errors))
+		}
+		for name, report := range want.Errors {
+			diff := CompareErrors(report.Err, got.Errors[name].Err)
+			if (diff == nil) != tt.match {
+				t.Errorf("%s: CompareErrors(%v, %v) = %v, want match %t", tt.op, report.Err, got.Errors[name].Err, diff, tt.match)
+			}
+		}
+	}
+}
+
+// rootedFS serves absolute paths from under root on the host and keeps its
+// own working directory, so anything created through the os package instead
+// ends up outside root.
+type rootedFS struct {
+	absfs.FileSystem
+	root   string
+	cwd    string
+	mkdirs []string
+}
+
+func (r *rootedFS) Mkdir(name string, perm os.FileMode) error {
+	r.mkdirs = append(r.mkdirs, name)
+	return r.FileSystem.Mkdir(filepath.Join(r.root, name), perm)
+}
+
+func (r *rootedFS) Create(name string) (absfs.File, error) {
+	return r.FileSystem.Create(filepath.Join(r.root, name))
+}
+
+func (r *rootedFS) Stat(name string) (os.FileInfo, error) {
+	return r.FileSystem.Stat(filepath.Join(r.root, name))
+}
+
+func (r *rootedFS) RemoveAll(name string) error {
+	return r.FileSystem.RemoveAll(filepath.Join(r.root, name))
+}
+
+func (r *rootedFS) Getwd() (string, error) { return r.cwd, nil }
+
+func (r *rootedFS) Chdir(dir string) error {
+	r.cwd = dir
+	return nil
+}
+
+func TestFsTestDirUsesFileSystem(t *testing.T) {
+	fsys := &rootedFS{FileSystem: osFS{}, root: t.TempDir(), cwd: "/"}
+	testdir, cleanup, err := FsTestDir(fsys, "/fstesting-rooted")
+	if err != nil {
+		t.Fatalf("FsTestDir: %s", err)
+	}
+	if fsys.cwd != testdir {
+		t.Errorf("working directory = %q, want %q", fsys.cwd, testdir)
+	}
+
+	for _, condition := range []string{"created", "dir"} {
+		name, err := pretest(fsys, testdir, &Testcase{TestNo: 1, PreCondition: condition})
+		if err != nil {
+			t.Fatalf("pretest(%s): %s", condition, err)
+		}
+		if _, err := fsys.Stat(name); err != nil {
+			t.Errorf("pretest(%s) did not create %q through the FileSystem: %s", condition, name, err)
+		}
+	}
+
+	for _, name := range fsys.mkdirs {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%q exists on the host, want it only under %q", name, fsys.root)
+		}
+	}
+	want := []string{"/fstesting-rooted", testdir, filepath.Join(testdir, "fstestingDir00000001")}
+	if strings.Join(fsys.mkdirs, ",") != strings.Join(want, ",") {
+		t.Errorf("Mkdir called with %q, want %q", fsys.mkdirs, want)
+	}
+
+	cleanup()
+	if _, err := fsys.Stat(testdir); !os.IsNotExist(err) {
+		t.Errorf("Stat(%q) after cleanup = %v, want not exist", testdir, err)
+	}
+}
+
+func TestFsTestDirBackToBack(t *testing.T) {
+	fsys := &rootedFS{FileSystem: osFS{}, root: t.TempDir(), cwd: "/"}
+	first, cleanup1, err := FsTestDir(fsys, "/fstesting-twice")
+	if err != nil {
+		t.Fatalf("first FsTestDir: %s", err)
+	}
+	defer cleanup1()
+	second, cleanup2, err := FsTestDir(fsys, "/fstesting-twice")
+	if err != nil {
+		t.Fatalf("second FsTestDir: %s", err)
+	}
+	defer cleanup2()
+
+	if first == second {
+		t.Fatalf("FsTestDir returned %q twice", first)
+	}
+	for _, dir := range []string{first, second} {
+		info, err := fsys.Stat(dir)
+		if err != nil || !info.IsDir() {
+			t.Errorf("Stat(%q) = %v, %v, want a directory", dir, info, err)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package fstesting
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// RunWithTimeout runs s like s.Run, but every FileSystem and File operation
+// is watched and the test binary panics with a dump of all goroutines if a
+// single operation takes longer than d. This turns an implementation that
+// hangs, such as one following a symlink loop forever, into a failure that
+// names the operation instead of a test timeout.
+//
+// Operations are watched through a wrapper around s.FS. The wrapper passes
+// on absfs.SymLinker if s.FS implements it, but hides the other optional
+// interfaces of the FileSystem and its Files, so the groups testing them
+// are skipped.
+func RunWithTimeout(t *testing.T, s *Suite, d time.Duration) {
+	base := s.FS
+	w := &timeoutFS{FileSystem: base, d: d}
+	if sl, ok := base.(absfs.SymLinker); ok {
+		s.FS = &timeoutSymlinkFS{w, sl}
+	} else {
+		s.FS = w
+	}
+	defer func() { s.FS = base }()
+	s.Run(t)
+}
+
+// timeoutFS wraps a FileSystem and panics if any operation takes longer than
+// d.
+type timeoutFS struct {
+	absfs.FileSystem
+	d time.Duration
+}
+
+// watch calls fn and panics with a dump of all goroutines if it has not
+// returned after tfs.d. The panic happens on another goroutine, as fn may
+// never return, which ends the test binary.
+func (tfs *timeoutFS) watch(op, name string, fn func()) {
+	timer := time.AfterFunc(tfs.d, func() {
+		buf := make([]byte, 1<<20)
+		buf = buf[:runtime.Stack(buf, true)]
+		fmt.Fprintf(os.Stderr, "fstesting: %s(%q) did not return within %s\n\n%s\n", op, name, tfs.d, buf)
+		panic(fmt.Sprintf("fstesting: %s(%q) did not return within %s", op, name, tfs.d))
+	})
+	defer timer.Stop()
+	fn()
+}
+
+func (tfs *timeoutFS) OpenFile(name string, flag int, perm os.FileMode) (f absfs.File, err error) {
+	tfs.watch("OpenFile", name, func() { f, err = tfs.FileSystem.OpenFile(name, flag, perm) })
+	if err != nil {
+		return nil, err
+	}
+	return &timeoutFile{f, tfs, name}, nil
+}
+
+func (tfs *timeoutFS) Open(name string) (absfs.File, error) {
+	return tfs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (tfs *timeoutFS) Create(name string) (absfs.File, error) {
+	return tfs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (tfs *timeoutFS) Mkdir(name string, perm os.FileMode) (err error) {
+	tfs.watch("Mkdir", name, func() { err = tfs.FileSystem.Mkdir(name, perm) })
+	return err
+}
+
+func (tfs *timeoutFS) MkdirAll(name string, perm os.FileMode) (err error) {
+	tfs.watch("MkdirAll", name, func() { err = tfs.FileSystem.MkdirAll(name, perm) })
+	return err
+}
+
+func (tfs *timeoutFS) Remove(name string) (err error) {
+	tfs.watch("Remove", name, func() { err = tfs.FileSystem.Remove(name) })
+	return err
+}
+
+func (tfs *timeoutFS) RemoveAll(name string) (err error) {
+	tfs.watch("RemoveAll", name, func() { err = tfs.FileSystem.RemoveAll(name) })
+	return err
+}
+
+func (tfs *timeoutFS) Rename(oldpath, newpath string) (err error) {
+	tfs.watch("Rename", oldpath, func() { err = tfs.FileSystem.Rename(oldpath, newpath) })
+	return err
+}
+
+func (tfs *timeoutFS) Stat(name string) (info os.FileInfo, err error) {
+	tfs.watch("Stat", name, func() { info, err = tfs.FileSystem.Stat(name) })
+	return info, err
+}
+
+func (tfs *timeoutFS) Chmod(name string, mode os.FileMode) (err error) {
+	tfs.watch("Chmod", name, func() { err = tfs.FileSystem.Chmod(name, mode) })
+	return err
+}
+
+func (tfs *timeoutFS) Chtimes(name string, atime time.Time, mtime time.Time) (err error) {
+	tfs.watch("Chtimes", name, func() { err = tfs.FileSystem.Chtimes(name, atime, mtime) })
+	return err
+}
+
+func (tfs *timeoutFS) Chown(name string, uid, gid int) (err error) {
+	tfs.watch("Chown", name, func() { err = tfs.FileSystem.Chown(name, uid, gid) })
+	return err
+}
+
+func (tfs *timeoutFS) ReadDir(name string) (entries []fs.DirEntry, err error) {
+	tfs.watch("ReadDir", name, func() { entries, err = tfs.FileSystem.ReadDir(name) })
+	return entries, err
+}
+
+func (tfs *timeoutFS) ReadFile(name string) (data []byte, err error) {
+	tfs.watch("ReadFile", name, func() { data, err = tfs.FileSystem.ReadFile(name) })
+	return data, err
+}
+
+func (tfs *timeoutFS) Sub(dir string) (sub fs.FS, err error) {
+	tfs.watch("Sub", dir, func() { sub, err = tfs.FileSystem.Sub(dir) })
+	return sub, err
+}
+
+func (tfs *timeoutFS) Chdir(dir string) (err error) {
+	tfs.watch("Chdir", dir, func() { err = tfs.FileSystem.Chdir(dir) })
+	return err
+}
+
+func (tfs *timeoutFS) Getwd() (dir string, err error) {
+	tfs.watch("Getwd", "", func() { dir, err = tfs.FileSystem.Getwd() })
+	return dir, err
+}
+
+func (tfs *timeoutFS) Truncate(name string, size int64) (err error) {
+	tfs.watch("Truncate", name, func() { err = tfs.FileSystem.Truncate(name, size) })
+	return err
+}
+
+// timeoutSymlinkFS is a timeoutFS for a FileSystem that implements
+// absfs.SymLinker.
+type timeoutSymlinkFS struct {
+	*timeoutFS
+	sl absfs.SymLinker
+}
+
+func (tfs *timeoutSymlinkFS) Lstat(name string) (info os.FileInfo, err error) {
+	tfs.watch("Lstat", name, func() { info, err = tfs.sl.Lstat(name) })
+	return info, err
+}
+
+func (tfs *timeoutSymlinkFS) Lchown(name string, uid, gid int) (err error) {
+	tfs.watch("Lchown", name, func() { err = tfs.sl.Lchown(name, uid, gid) })
+	return err
+}
+
+func (tfs *timeoutSymlinkFS) Readlink(name string) (target string, err error) {
+	tfs.watch("Readlink", name, func() { target, err = tfs.sl.Readlink(name) })
+	return target, err
+}
+
+func (tfs *timeoutSymlinkFS) Symlink(oldname, newname string) (err error) {
+	tfs.watch("Symlink", newname, func() { err = tfs.sl.Symlink(oldname, newname) })
+	return err
+}
+
+type timeoutFile struct {
+	absfs.File
+	fs   *timeoutFS
+	name string
+}
+
+func (f *timeoutFile) Read(p []byte) (n int, err error) {
+	f.fs.watch("Read", f.name, func() { n, err = f.File.Read(p) })
+	return n, err
+}
+
+func (f *timeoutFile) ReadAt(p []byte, off int64) (n int, err error) {
+	f.fs.watch("ReadAt", f.name, func() { n, err = f.File.ReadAt(p, off) })
+	return n, err
+}
+
+func (f *timeoutFile) Write(p []byte) (n int, err error) {
+	f.fs.watch("Write", f.name, func() { n, err = f.File.Write(p) })
+	return n, err
+}
+
+func (f *timeoutFile) WriteAt(p []byte, off int64) (n int, err error) {
+	f.fs.watch("WriteAt", f.name, func() { n, err = f.File.WriteAt(p, off) })
+	return n, err
+}
+
+func (f *timeoutFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *timeoutFile) Seek(offset int64, whence int) (ret int64, err error) {
+	f.fs.watch("Seek", f.name, func() { ret, err = f.File.Seek(offset, whence) })
+	return ret, err
+}
+
+func (f *timeoutFile) Truncate(size int64) (err error) {
+	f.fs.watch("Truncate", f.name, func() { err = f.File.Truncate(size) })
+	return err
+}
+
+func (f *timeoutFile) Sync() (err error) {
+	f.fs.watch("Sync", f.name, func() { err = f.File.Sync() })
+	return err
+}
+
+func (f *timeoutFile) Stat() (info os.FileInfo, err error) {
+	f.fs.watch("File.Stat", f.name, func() { info, err = f.File.Stat() })
+	return info, err
+}
+
+func (f *timeoutFile) ReadDir(n int) (entries []fs.DirEntry, err error) {
+	f.fs.watch("File.ReadDir", f.name, func() { entries, err = f.File.ReadDir(n) })
+	return entries, err
+}
+
+func (f *timeoutFile) Readdir(n int) (infos []os.FileInfo, err error) {
+	f.fs.watch("Readdir", f.name, func() { infos, err = f.File.Readdir(n) })
+	return infos, err
+}
+
+func (f *timeoutFile) Readdirnames(n int) (names []string, err error) {
+	f.fs.watch("Readdirnames", f.name, func() { names, err = f.File.Readdirnames(n) })
+	return names, err
+}
+
+func (f *timeoutFile) Close() (err error) {
+	f.fs.watch("Close", f.name, func() { err = f.File.Close() })
+	return err
+}
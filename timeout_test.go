@@ -0,0 +1,63 @@
+package fstesting
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunWithTimeout(t *testing.T) {
+	s := &Suite{FS: osFS{}, TestDir: t.TempDir(), Features: OSFeatures()}
+	RunWithTimeout(t, s, time.Minute)
+	if _, ok := s.FS.(osFS); !ok {
+		t.Errorf("RunWithTimeout left s.FS as %T, want osFS", s.FS)
+	}
+}
+
+// blockingFS never returns from Stat.
+type blockingFS struct {
+	osFS
+}
+
+func (blockingFS) Stat(name string) (os.FileInfo, error) {
+	select {}
+}
+
+// TestRunWithTimeoutFires runs itself in a child process with
+// FSTESTING_BLOCK set, where the watchdog has to end the test binary.
+func TestRunWithTimeoutFires(t *testing.T) {
+	if os.Getenv("FSTESTING_BLOCK") != "" {
+		s := &Suite{FS: blockingFS{}, TestDir: t.TempDir(), Features: OSFeatures()}
+		RunWithTimeout(t, s, 100*time.Millisecond)
+		return
+	}
+	if testing.Short() {
+		t.Skip("skipping child process in short mode")
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRunWithTimeoutFires$")
+	cmd.Env = append(os.Environ(), "FSTESTING_BLOCK=1")
+	done := make(chan struct{})
+	var out []byte
+	var err error
+	go func() {
+		out, err = cmd.CombinedOutput()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Minute):
+		cmd.Process.Kill()
+		<-done
+		t.Fatal("child test did not end within a minute")
+	}
+
+	if err == nil {
+		t.Fatalf("child test succeeded, want the watchdog to panic:\n%s", out)
+	}
+	if !strings.Contains(string(out), "fstesting: Stat(") || !strings.Contains(string(out), "did not return within 100ms") {
+		t.Errorf("child output does not name the blocked operation:\n%s", out)
+	}
+}
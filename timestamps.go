@@ -0,0 +1,119 @@
+package fstesting
+
+import (
+	"fmt"
+	"path"
+	"testing"
+	"time"
+)
+
+// testTimestamps covers setting and reporting file times.
+func (s *Suite) testTimestamps(t *testing.T, testDir string) {
+	dir := path.Join(testDir, "timestamps")
+	s.mkdir(t, dir)
+
+	s.run(t, "Atime", func(t *testing.T) {
+		name := path.Join(dir, "atime")
+		s.writeFile(t, name, []byte("atime"))
+
+		// An access time older than the modification time is updated by
+		// reads under relatime as well as strictatime.
+		atime := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+		mtime := time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC)
+		err := s.FS.Chtimes(name, atime, mtime)
+		if err != nil {
+			t.Fatalf("Chtimes(%q): %s", name, err)
+		}
+		info, err := s.FS.Stat(name)
+		if err != nil {
+			t.Fatalf("Stat(%q): %s", name, err)
+		}
+		got, ok := accessTime(info)
+		if !ok {
+			s.skip(t, fmt.Sprintf("access time not reported by FileInfo of type %T", info.Sys()))
+		}
+		if !got.Equal(atime) {
+			t.Errorf("access time after Chtimes(%q) = %s, want %s", name, got, atime)
+		}
+
+		_, err = s.FS.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile(%q): %s", name, err)
+		}
+		info, err = s.FS.Stat(name)
+		if err != nil {
+			t.Fatalf("Stat(%q): %s", name, err)
+		}
+		got, _ = accessTime(info)
+		updated := got.After(atime)
+		switch {
+		case s.Features.AtimeOnRead && !updated:
+			t.Errorf("access time after ReadFile(%q) = %s, want it updated", name, got)
+		case !s.Features.AtimeOnRead:
+			t.Logf("reading updates access time: %t", updated)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("ModTime() after ReadFile(%q) = %s, want %s", name, info.ModTime(), mtime)
+		}
+	})
+	s.run(t, "ChtimesOmit", func(t *testing.T) {
+		name := path.Join(dir, "omit")
+		s.writeFile(t, name, []byte("omit"))
+
+		// As with os.Chtimes, a zero time.Time leaves the corresponding
+		// time unchanged, like UTIME_OMIT.
+		atime := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+		mtime := time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC)
+		later := time.Date(2003, 1, 1, 0, 0, 0, 0, time.UTC)
+		for _, tc := range []struct {
+			atime, mtime         time.Time
+			wantAtime, wantMtime time.Time
+		}{
+			{atime, mtime, atime, mtime},
+			{time.Time{}, later, atime, later},
+			{later, time.Time{}, later, later},
+			{time.Time{}, time.Time{}, later, later},
+		} {
+			err := s.FS.Chtimes(name, tc.atime, tc.mtime)
+			if err != nil {
+				t.Fatalf("Chtimes(%q, %s, %s): %s", name, tc.atime, tc.mtime, err)
+			}
+			info, err := s.FS.Stat(name)
+			if err != nil {
+				t.Fatalf("Stat(%q): %s", name, err)
+			}
+			if !info.ModTime().Equal(tc.wantMtime) {
+				t.Errorf("ModTime() after Chtimes(%q, %s, %s) = %s, want %s", name, tc.atime, tc.mtime, info.ModTime(), tc.wantMtime)
+			}
+			got, ok := accessTime(info)
+			if ok && !got.Equal(tc.wantAtime) {
+				t.Errorf("access time after Chtimes(%q, %s, %s) = %s, want %s", name, tc.atime, tc.mtime, got, tc.wantAtime)
+			}
+		}
+	})
+}
+
+// testCreationTime checks that a new file reports a creation time from around
+// when it was created and no later than its modification time.
+func (s *Suite) testCreationTime(t *testing.T, testDir string) {
+	name := path.Join(testDir, "birthtime")
+	before := time.Now().Add(-time.Minute)
+	s.writeFile(t, name, []byte("birthtime"))
+
+	info, err := s.FS.Stat(name)
+	if err != nil {
+		t.Fatalf("Stat(%q): %s", name, err)
+	}
+	got, ok := birthTime(info)
+	if !ok {
+		s.skip(t, fmt.Sprintf("creation time not reported by FileInfo of type %T", info.Sys()))
+	}
+	switch {
+	case got.IsZero():
+		t.Errorf("creation time of %q is zero", name)
+	case got.After(info.ModTime()):
+		t.Errorf("creation time of %q is %s, after its modification time %s", name, got, info.ModTime())
+	case got.Before(before):
+		t.Errorf("creation time of new file %q is %s, long before it was created", name, got)
+	}
+}
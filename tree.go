@@ -0,0 +1,165 @@
+package fstesting
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/absfs/absfs"
+)
+
+// TreeEntry describes a file, directory or symbolic link in a TreeSpec.
+// Entries with a zero Mode keep the permissions they are created with, 0666
+// for files and 0755 for directories less any umask the FileSystem applies.
+type TreeEntry struct {
+	Dir     bool        // the entry is a directory
+	Target  string      // if set the entry is a symbolic link to Target
+	Content string      // the content of a regular file
+	Mode    os.FileMode // permission bits, applied with Chmod if not zero
+}
+
+// TreeSpec describes a tree of files by mapping slash separated paths,
+// relative to the root of the tree, to their entries. Missing parent
+// directories are created with mode 0755.
+type TreeSpec map[string]TreeEntry
+
+// BuildTree creates the tree described by spec under root in fs. Symbolic
+// links require fs to be an absfs.SymLinker. Permissions are applied after
+// every entry has been created, so directories may be made read only, and
+// are skipped if fs does not implement Chmod.
+func BuildTree(fs absfs.FileSystem, root string, spec TreeSpec) error {
+	err := fs.MkdirAll(root, 0755)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(spec))
+	for name := range spec {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entry := spec[name]
+		full := path.Join(root, name)
+		err := fs.MkdirAll(path.Dir(full), 0755)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.Target != "":
+			sl, ok := fs.(absfs.SymLinker)
+			if !ok {
+				return fmt.Errorf("creating symlink %q: %w", full, absfs.ErrNotImplemented)
+			}
+			err = sl.Symlink(entry.Target, full)
+		case entry.Dir:
+			err = fs.MkdirAll(full, 0755)
+		default:
+			err = writeAll(fs, full, []byte(entry.Content))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Children before parents, in case a parent loses write permission.
+	for i := len(names) - 1; i >= 0; i-- {
+		entry := spec[names[i]]
+		if entry.Mode == 0 || entry.Target != "" {
+			continue
+		}
+		err := fs.Chmod(path.Join(root, names[i]), entry.Mode)
+		if err != nil && !errors.Is(err, absfs.ErrNotImplemented) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseTreeSpec parses a TreeSpec from text with one entry per line. Blank
+// lines and lines starting with # are ignored, and paths may not contain
+// ":" or " -> ".
+//
+//	dir/                   a directory
+//	dir/file               an empty file
+//	dir/file 0600          a file with mode 0600
+//	dir/file: content      a file holding "content"
+//	dir/file 0600: "a\nb"  a file holding the Go quoted string
+//	dir/link -> file       a symbolic link to file
+func ParseTreeSpec(text string) (TreeSpec, error) {
+	spec := make(TreeSpec)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, entry, err := parseTreeLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNo, err)
+		}
+		spec[name] = entry
+	}
+	return spec, scanner.Err()
+}
+
+func parseTreeLine(line string) (string, TreeEntry, error) {
+	var entry TreeEntry
+	// Content may hold any string, so only the part before the first ":"
+	// is searched for a symbolic link.
+	content, hasContent := "", false
+	if i := strings.Index(line, ":"); i >= 0 {
+		content, hasContent = strings.TrimSpace(line[i+1:]), true
+		line = line[:i]
+	}
+
+	if i := strings.Index(line, " -> "); i >= 0 {
+		name := strings.TrimSpace(line[:i])
+		if hasContent {
+			return "", entry, fmt.Errorf("symbolic link %q has content", name)
+		}
+		entry.Target = strings.TrimSpace(line[i+4:])
+		return name, entry, nil
+	}
+
+	if hasContent {
+		if strings.HasPrefix(content, `"`) {
+			unquoted, err := strconv.Unquote(content)
+			if err != nil {
+				return "", entry, fmt.Errorf("bad content %s: %s", content, err)
+			}
+			content = unquoted
+		}
+		entry.Content = content
+	}
+
+	fields := strings.Fields(line)
+	switch len(fields) {
+	case 1:
+	case 2:
+		mode, err := strconv.ParseUint(fields[1], 8, 32)
+		if err != nil || mode > 0777 {
+			return "", entry, fmt.Errorf("bad mode %q", fields[1])
+		}
+		entry.Mode = os.FileMode(mode)
+	default:
+		return "", entry, fmt.Errorf("want a path and an optional mode, got %q", line)
+	}
+
+	name := fields[0]
+	if strings.HasSuffix(name, "/") {
+		if entry.Content != "" {
+			return "", entry, fmt.Errorf("directory %q has content", name)
+		}
+		entry.Dir = true
+		name = strings.TrimSuffix(name, "/")
+	}
+	return name, entry, nil
+}
@@ -0,0 +1,96 @@
+package fstesting
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseTreeSpec(t *testing.T) {
+	for _, tt := range []struct {
+		line  string
+		name  string
+		entry TreeEntry
+	}{
+		{"dir/", "dir", TreeEntry{Dir: true}},
+		{"dir/ 0700", "dir", TreeEntry{Dir: true, Mode: 0700}},
+		{"dir/file", "dir/file", TreeEntry{}},
+		{"dir/file 0600", "dir/file", TreeEntry{Mode: 0600}},
+		{"f: content", "f", TreeEntry{Content: "content"}},
+		{"f 0600: content", "f", TreeEntry{Content: "content", Mode: 0600}},
+		{`f: "a\nb"`, "f", TreeEntry{Content: "a\nb"}},
+		{`f: "a -> b"`, "f", TreeEntry{Content: "a -> b"}},
+		{"g: x -> y", "g", TreeEntry{Content: "x -> y"}},
+		{"h: a: b", "h", TreeEntry{Content: "a: b"}},
+		{"link -> file", "link", TreeEntry{Target: "file"}},
+		{"dir/link -> ../x/y", "dir/link", TreeEntry{Target: "../x/y"}},
+	} {
+		spec, err := ParseTreeSpec(tt.line)
+		if err != nil {
+			t.Errorf("ParseTreeSpec(%q): %s", tt.line, err)
+			continue
+		}
+		want := TreeSpec{tt.name: tt.entry}
+		if !reflect.DeepEqual(spec, want) {
+			t.Errorf("ParseTreeSpec(%q) = %+v, want %+v", tt.line, spec, want)
+		}
+	}
+}
+
+func TestParseTreeSpecErrors(t *testing.T) {
+	for _, line := range []string{
+		"f 0800",
+		"f 0600 extra",
+		`f: "unterminated`,
+		"dir/: content",
+		"link -> file: content",
+	} {
+		_, err := ParseTreeSpec(line)
+		if err == nil {
+			t.Errorf("ParseTreeSpec(%q) succeeded, want an error", line)
+		}
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	spec, err := ParseTreeSpec(`
+		# comments and blank lines are ignored
+
+		empty
+		a/b/file 0600: "x -> y"
+		a/dir/ 0700
+		a/link -> b/file
+	`)
+	if err != nil {
+		t.Fatalf("ParseTreeSpec: %s", err)
+	}
+	root := t.TempDir()
+	err = BuildTree(osFS{}, root, spec)
+	if err != nil {
+		t.Fatalf("BuildTree: %s", err)
+	}
+
+	for name, want := range map[string]string{"empty": "", "a/b/file": "x -> y"} {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			t.Errorf("ReadFile(%q): %s", name, err)
+		} else if string(data) != want {
+			t.Errorf("%q holds %q, want %q", name, data, want)
+		}
+	}
+	for name, want := range map[string]os.FileMode{"a/b/file": 0600, "a/dir": os.ModeDir | 0700} {
+		info, err := os.Stat(filepath.Join(root, name))
+		if err != nil {
+			t.Errorf("Stat(%q): %s", name, err)
+		} else if info.Mode() != want {
+			t.Errorf("%q has mode %s, want %s", name, info.Mode(), want)
+		}
+	}
+	target, err := os.Readlink(filepath.Join(root, "a/link"))
+	if err != nil {
+		t.Errorf("Readlink(%q): %s", "a/link", err)
+	} else if target != "b/file" {
+		t.Errorf("a/link points to %q, want %q", target, "b/file")
+	}
+}
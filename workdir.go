@@ -0,0 +1,175 @@
+package fstesting
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/absfs/absfs"
+)
+
+// testWorkingDirectory checks that Chdir and Getwd agree with each other and
+// that relative names resolve against the working directory as they do in
+// the os package.
+func (s *Suite) testWorkingDirectory(t *testing.T, testDir string) {
+	cwd, err := s.FS.Getwd()
+	if errors.Is(err, absfs.ErrNotImplemented) {
+		s.skip(t, "Getwd not implemented")
+	}
+	if err != nil {
+		t.Fatalf("Getwd: %s", err)
+	}
+	dir := path.Join(testDir, "workdir")
+	s.mkdir(t, dir)
+	err = s.FS.Chdir(dir)
+	if errors.Is(err, absfs.ErrNotImplemented) {
+		s.skip(t, "Chdir not implemented")
+	}
+	if err != nil {
+		t.Fatalf("Chdir(%q): %s", dir, err)
+	}
+	defer func() {
+		err := s.FS.Chdir(cwd)
+		if err != nil {
+			t.Errorf("restoring working directory Chdir(%q): %s", cwd, err)
+		}
+	}()
+
+	s.run(t, "Chdir", func(t *testing.T) {
+		got, err := s.FS.Getwd()
+		if err != nil {
+			t.Fatalf("Getwd: %s", err)
+		}
+		if got != dir {
+			// The FileSystem may report the directory by another name,
+			// such as a path with symlinks resolved. Accept it if it
+			// names the same directory.
+			marker := path.Join(dir, "marker")
+			s.writeFile(t, marker, []byte("marker"))
+			_, err := s.FS.Stat(path.Join(got, "marker"))
+			if err != nil {
+				t.Errorf("Getwd() = %q after Chdir(%q)", got, dir)
+			} else {
+				t.Logf("Getwd() = %q after Chdir(%q)", got, dir)
+			}
+		}
+		if !path.IsAbs(got) && !filepath.IsAbs(got) {
+			t.Errorf("Getwd() = %q, want an absolute path", got)
+		}
+
+		s.writeFile(t, "relative", []byte("relative"))
+		abs := path.Join(dir, "relative")
+		info, err := s.FS.Stat(abs)
+		if err != nil {
+			t.Fatalf("Stat(%q) of file created as %q: %s", abs, "relative", err)
+		}
+		if info.Size() != int64(len("relative")) {
+			t.Errorf("Stat(%q).Size() = %d, want %d", abs, info.Size(), len("relative"))
+		}
+		s.checkContent(t, abs, "relative")
+	})
+	s.run(t, "RelativePaths", func(t *testing.T) {
+		// The same sequence is run in the FileSystem and in a host
+		// directory, both from a working directory of rel/sub.
+		rel := path.Join(dir, "rel")
+		sub := path.Join(rel, "sub")
+		s.mkdir(t, sub)
+		host := filepath.Join(t.TempDir(), "sub")
+		err := os.Mkdir(host, 0777)
+		if err != nil {
+			t.Fatalf("os.Mkdir(%q): %s", host, err)
+		}
+
+		err = s.FS.Chdir(sub)
+		if err != nil {
+			t.Fatalf("Chdir(%q): %s", sub, err)
+		}
+		defer s.FS.Chdir(dir)
+
+		for _, o := range relativeOps {
+			info, err := o.fs(s.FS, o.name)
+			osinfo, oserr := o.os(filepath.Join(host, filepath.FromSlash(o.name)), o.name)
+			if diff := CompareErrors(oserr, err); diff != nil {
+				t.Errorf("%s(%q) in %q differs from os: %s", o.op, o.name, sub, diff)
+				continue
+			}
+			if info == nil || osinfo == nil {
+				continue
+			}
+			if info.IsDir() != osinfo.IsDir() || (!info.IsDir() && info.Size() != osinfo.Size()) {
+				t.Errorf("%s(%q) in %q = dir %t size %d, os gives dir %t size %d", o.op, o.name, sub,
+					info.IsDir(), info.Size(), osinfo.IsDir(), osinfo.Size())
+			}
+		}
+
+		// Check that the files landed relative to the working directory.
+		s.checkContent(t, path.Join(sub, "x", "y"), "x/y")
+		s.checkContent(t, path.Join(sub, "z"), "./z")
+		s.checkContent(t, path.Join(rel, "y"), "../y")
+	})
+}
+
+// relativeOp is an operation on a relative name, implemented for both an
+// absfs.FileSystem and the host filesystem.
+type relativeOp struct {
+	op   string
+	name string
+	fs   func(fs absfs.FileSystem, name string) (os.FileInfo, error)
+	os   func(hostName, name string) (os.FileInfo, error)
+}
+
+// relativeOps is the sequence run by the RelativePaths test. Creates write
+// the relative name as the content of the file.
+var relativeOps = []relativeOp{
+	relativeMkdir("x"),
+	relativeCreate("x/y"),
+	relativeCreate("./z"),
+	relativeCreate("../y"),
+	relativeStat("./x"),
+	relativeStat("x/y"),
+	relativeStat("./x/y"),
+	relativeStat("../y"),
+	relativeStat("../sub/z"),
+	relativeStat("x/../z"),
+	relativeStat("."),
+	relativeStat(".."),
+	relativeStat("./missing"),
+	relativeStat("../missing/y"),
+	relativeCreate("./z/w"),
+	relativeMkdir("./x"),
+}
+
+func relativeMkdir(name string) relativeOp {
+	return relativeOp{"Mkdir", name,
+		func(fs absfs.FileSystem, name string) (os.FileInfo, error) {
+			return nil, fs.Mkdir(name, 0777)
+		},
+		func(hostName, name string) (os.FileInfo, error) {
+			return nil, os.Mkdir(hostName, 0777)
+		},
+	}
+}
+
+func relativeCreate(name string) relativeOp {
+	return relativeOp{"Create", name,
+		func(fs absfs.FileSystem, name string) (os.FileInfo, error) {
+			return nil, writeAll(fs, name, []byte(name))
+		},
+		func(hostName, name string) (os.FileInfo, error) {
+			return nil, os.WriteFile(hostName, []byte(name), 0666)
+		},
+	}
+}
+
+func relativeStat(name string) relativeOp {
+	return relativeOp{"Stat", name,
+		func(fs absfs.FileSystem, name string) (os.FileInfo, error) {
+			return fs.Stat(name)
+		},
+		func(hostName, name string) (os.FileInfo, error) {
+			return os.Stat(hostName)
+		},
+	}
+}
@@ -0,0 +1,234 @@
+package fstesting
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// WrapperSuite tests a FileSystem that wraps another FileSystem, such as a
+// caching, compressing or read only layer. Tests write through Wrapped and,
+// where the wrapper claims to be transparent, check the result in Base.
+type WrapperSuite struct {
+	// Wrapped is the wrapper under test.
+	Wrapped absfs.FileSystem
+
+	// Base is the FileSystem that Wrapped stores its data in.
+	Base absfs.FileSystem
+
+	// TestDir is the directory where test directories are created. If empty
+	// Base.TempDir() is used.
+	TestDir string
+
+	// TransformsData is set if file contents or names in Base differ from
+	// what is written through Wrapped, e.g. compression or encryption.
+	TransformsData bool
+
+	// TransformsMeta is set if modes or times in Base differ from what is
+	// set through Wrapped.
+	TransformsMeta bool
+
+	// ReadOnly is set if Wrapped rejects all modifications.
+	ReadOnly bool
+}
+
+// Run runs every wrapper test as a subtest of t.
+func (s *WrapperSuite) Run(t *testing.T) {
+	testDir := s.setup(t)
+
+	t.Run("MetaPassthrough", func(t *testing.T) {
+		if s.ReadOnly {
+			t.Skip("wrapper is read only")
+		}
+		s.testMetaPassthrough(t, testDir)
+	})
+
+	t.Run("DirListingPassthrough", func(t *testing.T) {
+		s.testDirListingPassthrough(t, testDir)
+	})
+
+	t.Run("SeekThroughWrapper", func(t *testing.T) {
+		s.testSeekThroughWrapper(t, testDir)
+	})
+}
+
+// setup creates the test directory for a run. Read only wrappers can not
+// create it, so it is created in Base instead.
+func (s *WrapperSuite) setup(t *testing.T) string {
+	t.Helper()
+	if s.ReadOnly {
+		return makeTestDir(t, s.Base, s.TestDir)
+	}
+	base := s.TestDir
+	if base == "" {
+		base = s.Base.TempDir()
+	}
+	return makeTestDir(t, s.Wrapped, base)
+}
+
+// creator returns the FileSystem used to create test fixtures, which is Base
+// for read only wrappers and Wrapped otherwise.
+func (s *WrapperSuite) creator() absfs.FileSystem {
+	if s.ReadOnly {
+		return s.Base
+	}
+	return s.Wrapped
+}
+
+// testMetaPassthrough sets a mode and modification time through the wrapper.
+// Transparent wrappers must pass both through to Base; all wrappers must
+// report them back unchanged.
+func (s *WrapperSuite) testMetaPassthrough(t *testing.T, testDir string) {
+	name := path.Join(testDir, "meta")
+	mode := os.FileMode(0640)
+	mtime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	err := writeAll(s.Wrapped, name, []byte("meta"))
+	if err != nil {
+		t.Fatalf("writing %q: %s", name, err)
+	}
+	err = s.Wrapped.Chmod(name, mode)
+	if err != nil {
+		t.Fatalf("Chmod(%q, %s): %s", name, mode, err)
+	}
+	err = s.Wrapped.Chtimes(name, mtime, mtime)
+	if err != nil {
+		t.Fatalf("Chtimes(%q): %s", name, err)
+	}
+
+	labels := []string{"wrapper"}
+	filesystems := []absfs.FileSystem{s.Wrapped}
+	if !s.TransformsMeta {
+		labels = append(labels, "base")
+		filesystems = append(filesystems, s.Base)
+	}
+
+	for i, fs := range filesystems {
+		info, err := fs.Stat(name)
+		if err != nil {
+			t.Errorf("%s Stat(%q): %s", labels[i], name, err)
+			continue
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("%s mode = %s, want %s", labels[i], info.Mode().Perm(), mode)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("%s mtime = %s, want %s", labels[i], info.ModTime(), mtime)
+		}
+	}
+}
+
+// testDirListingPassthrough creates files and directories and checks that
+// the wrapper lists all of them. For wrappers that do not transform data the
+// listing must match Base exactly. Read only wrappers are populated through
+// Base.
+func (s *WrapperSuite) testDirListingPassthrough(t *testing.T, testDir string) {
+	dir := path.Join(testDir, "listing")
+	files := []string{"a.txt", "b.txt", "c.txt"}
+	dirs := []string{"d1", "d2"}
+
+	create := s.creator()
+	err := create.Mkdir(dir, 0777)
+	if err != nil {
+		t.Fatalf("Mkdir(%q): %s", dir, err)
+	}
+	for _, name := range files {
+		err := writeAll(create, path.Join(dir, name), []byte(name))
+		if err != nil {
+			t.Fatalf("writing %q: %s", name, err)
+		}
+	}
+	for _, name := range dirs {
+		err := create.Mkdir(path.Join(dir, name), 0777)
+		if err != nil {
+			t.Fatalf("Mkdir(%q): %s", name, err)
+		}
+	}
+	want := append(append([]string{}, files...), dirs...)
+	sort.Strings(want)
+
+	names := func(fs absfs.FileSystem) []string {
+		entries, err := fs.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("ReadDir(%q): %s", dir, err)
+		}
+		var list []string
+		for _, e := range entries {
+			list = append(list, e.Name())
+		}
+		sort.Strings(list)
+		return list
+	}
+
+	got := names(s.Wrapped)
+	if s.TransformsData {
+		present := make(map[string]bool)
+		for _, name := range got {
+			present[name] = true
+		}
+		for _, name := range want {
+			if !present[name] {
+				t.Errorf("wrapper listing %q is missing %q", got, name)
+			}
+		}
+		return
+	}
+
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("wrapper listing = %q, want %q", got, want)
+	}
+	got = names(s.Base)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("base listing = %q, want %q", got, want)
+	}
+}
+
+// testSeekThroughWrapper reads a 64KB file through the wrapper at a series of
+// offsets, forwards and backwards. Wrappers that compress or encrypt in blocks
+// commonly get random access wrong.
+func (s *WrapperSuite) testSeekThroughWrapper(t *testing.T, testDir string) {
+	name := path.Join(testDir, "seek")
+	payload := make([]byte, 64<<10)
+	for i := range payload {
+		payload[i] = byte(i * 7 / 3)
+	}
+	err := writeAll(s.creator(), name, payload)
+	if err != nil {
+		t.Fatalf("writing %q: %s", name, err)
+	}
+
+	f, err := s.Wrapped.Open(name)
+	if err != nil {
+		t.Fatalf("Open(%q): %s", name, err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, 100)
+	for _, offset := range []int64{0, 1, 4095, 4096, 32768, 65000, 65436, 40000, 4097, 0} {
+		n, err := f.Seek(offset, io.SeekStart)
+		if errors.Is(err, absfs.ErrNotImplemented) {
+			t.Skip("capability limitation: wrapper files do not support Seek")
+		}
+		if err != nil {
+			t.Fatalf("Seek(%d): %s", offset, err)
+		}
+		if n != offset {
+			t.Fatalf("Seek(%d) = %d", offset, n)
+		}
+		_, err = io.ReadFull(f, buf)
+		if err != nil {
+			t.Fatalf("Read at %d: %s", offset, err)
+		}
+		if !bytes.Equal(buf, payload[offset:offset+int64(len(buf))]) {
+			t.Errorf("Read at %d does not match the written payload", offset)
+		}
+	}
+}